@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// openAPISpec is the OpenAPI 3 document of /api/v1.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// apiRequest is the body of POST /api/v1/deps.
+type apiRequest struct {
+	Repo     string   `json:"repo,omitempty"`    // owner/repo[@ref]
+	Content  string   `json:"content,omitempty"` // go.mod, go list -m -json all or go mod graph
+	Indirect bool     `json:"indirect,omitempty"`
+	Fields   []string `json:"fields,omitempty"` // dependency fields, default all
+	Format   string   `json:"format,omitempty"` // one of outputFormats, default json
+}
+
+// apiDep is a dependency in a v1 response.
+type apiDep struct {
+	Name        string       `json:"name"`
+	Version     string       `json:"version"`
+	Description string       `json:"description"`
+	URL         string       `json:"url"`
+	Indirect    bool         `json:"indirect"`
+	Replace     string       `json:"replace,omitempty"`
+	License     *License     `json:"license,omitempty"`
+	Vulns       []Vuln       `json:"vulns,omitempty"`
+	Deprecated  *Deprecation `json:"deprecated,omitempty"`
+	Retracted   *Retraction  `json:"retracted,omitempty"`
+	Go          *GoCompat    `json:"go,omitempty"`
+	Changes     *Changes     `json:"changes,omitempty"`
+	Health      *Health      `json:"health,omitempty"`
+	Warnings    []Warning    `json:"warnings,omitempty"`
+	Fork        *Fork        `json:"fork,omitempty"`
+}
+
+// apiFields are the JSON names of apiDep fields, for the fields option.
+var apiFields = []string{
+	"name", "version", "description", "url", "indirect", "replace", "license", "vulns",
+	"deprecated", "retracted", "go", "changes", "health", "warnings", "fork",
+}
+
+func newAPIDep(p PkgInfo) apiDep {
+	return apiDep{
+		Name:        p.Name,
+		Version:     p.Version,
+		Description: p.Desc,
+		URL:         p.URL,
+		Indirect:    p.Indirect,
+		Replace:     p.Replace,
+		License:     p.License,
+		Vulns:       p.Vulns,
+		Deprecated:  p.Deprecated,
+		Retracted:   p.Retracted,
+		Go:          p.Go,
+		Changes:     p.Changes,
+		Health:      p.Health,
+		Warnings:    p.Warnings,
+		Fork:        p.Fork,
+	}
+}
+
+// apiError is an error in a v1 response, Module is set for errors of a
+// single dependency.
+type apiError struct {
+	Module  string `json:"module,omitempty"`
+	Message string `json:"message"`
+}
+
+// apiTimings are the durations of the request phases in milliseconds.
+type apiTimings struct {
+	Fetch   float64 `json:"fetchMs"`   // getting the go.mod
+	Resolve float64 `json:"resolveMs"` // describing the dependencies
+	Total   float64 `json:"totalMs"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// apiResponse is the v1 response envelope.
+// Dependencies is set for json format, Document for the other formats.
+type apiResponse struct {
+	Module       string          `json:"module,omitempty"`
+	Go           string          `json:"go,omitempty"`
+	Format       string          `json:"format,omitempty"`
+	Dependencies []any           `json:"dependencies,omitzero"`
+	Document     json.RawMessage `json:"document,omitzero"`
+	Errors       []apiError      `json:"errors"`
+	Timings      apiTimings      `json:"timings"`
+}
+
+// check validates the request and sets defaults.
+func (req *apiRequest) check() error {
+	if req.Repo != "" && req.Content != "" {
+		return fmt.Errorf("provide repo or content, not both")
+	}
+	if req.Repo == "" && req.Content == "" {
+		return fmt.Errorf("missing repo or content")
+	}
+
+	if req.Format == "" {
+		req.Format = formatJSON
+	}
+	if err := checkOutputFormat(req.Format); err != nil {
+		return err
+	}
+
+	if len(req.Fields) > 0 && req.Format != formatJSON {
+		return fmt.Errorf("fields: only with %s format", formatJSON)
+	}
+	for _, f := range req.Fields {
+		if !slices.Contains(apiFields, f) {
+			return fmt.Errorf("%q: unknown field (valid: %s)", f, strings.Join(apiFields, ", "))
+		}
+	}
+	return nil
+}
+
+// selectFields returns dep with only the fields in names, all if names is
+// empty.
+func selectFields(dep apiDep, names []string) (any, error) {
+	if len(names) == 0 {
+		return dep, nil
+	}
+
+	data, err := json.Marshal(dep)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k := range m {
+		if !slices.Contains(names, k) {
+			delete(m, k)
+		}
+	}
+	return m, nil
+}
+
+// apiDeps describes reqs. Failed lookups are reported in errors instead of
+// failing the request, unresolved dependencies are kept if keep is set.
+func apiDeps(ctx context.Context, reqs []requirement, mainGo string, keep bool, cache repoCache) ([]PkgInfo, []apiError, error) {
+	var pkgs []PkgInfo
+	errs := []apiError{}
+	for _, req := range reqs {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		info, ok, err := reqInfo(ctx, req, mainGo, cache)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		switch {
+		case err != nil:
+			errs = append(errs, apiError{Module: req.Mod.Path, Message: err.Error()})
+		case !ok:
+			errs = append(errs, apiError{Module: req.Mod.Path, Message: "can't find repository"})
+			if keep {
+				info = PkgInfo{Name: req.Mod.Path, Version: req.Mod.Version, Indirect: req.Indirect}
+				if req.Replace != nil {
+					info.Replace = req.Replace.String()
+				}
+				pkgs = append(pkgs, info)
+			}
+		default:
+			// depInfo reports proxy errors in the description.
+			if msg, found := strings.CutPrefix(info.Desc, "error: "); found {
+				errs = append(errs, apiError{Module: req.Mod.Path, Message: msg})
+				info.Desc = ""
+			}
+			pkgs = append(pkgs, info)
+		}
+	}
+	return pkgs, errs, nil
+}
+
+// apiDocument renders pkgs in a non JSON format for the response Document.
+func apiDocument(main string, pkgs []PkgInfo, format string) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if err := writePkgs(&buf, main, pkgs, format); err != nil {
+		return nil, err
+	}
+	if isSBOM(format) {
+		return buf.Bytes(), nil
+	}
+	return json.Marshal(buf.String())
+}
+
+// writeAPI writes a v1 response.
+func writeAPI(w http.ResponseWriter, resp apiResponse, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("encode response", "error", err)
+	}
+}
+
+// handleAPIv1 describes the go.mod of a repository or content,
+// POST /api/v1/deps with an apiRequest body.
+func (s *server) handleAPIv1(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var resp apiResponse
+	fail := func(err error, status int) {
+		resp.Errors = []apiError{{Message: err.Error()}}
+		resp.Timings.Total = millis(time.Since(start))
+		writeAPI(w, resp, status)
+	}
+
+	var req apiRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFormBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		fail(fmt.Errorf("bad request body - %w", err), http.StatusBadRequest)
+		return
+	}
+	if err := req.check(); err != nil {
+		fail(err, http.StatusBadRequest)
+		return
+	}
+	resp.Format = req.Format
+
+	data := []byte(req.Content)
+	if req.Repo != "" {
+		repo, ref, err := parseRepoRef(req.Repo)
+		if err != nil {
+			fail(err, http.StatusBadRequest)
+			return
+		}
+		data, err = repoGoMod(r.Context(), repo, ref)
+		if err != nil {
+			fail(err, http.StatusBadGateway)
+			return
+		}
+	}
+	resp.Timings.Fetch = millis(time.Since(start))
+	resp.Module, resp.Go = mainModule(data), mainGoVersion(data)
+
+	// SBOMs list every module, as in the command line.
+	sbom := isSBOM(req.Format)
+	reqs, err := parseRequires(data, inputFormat, req.Indirect || sbom)
+	if err != nil {
+		fail(err, http.StatusBadRequest)
+		return
+	}
+	if err := checkModuleCount(len(reqs)); err != nil {
+		fail(err, requestErrorStatus(err))
+		return
+	}
+
+	var mainGo string
+	if checkGoCompat {
+		mainGo = resp.Go
+	}
+	resolveStart := time.Now()
+	pkgs, errs, err := apiDeps(r.Context(), reqs, mainGo, sbom, s.cache)
+	if err != nil {
+		fail(err, http.StatusServiceUnavailable)
+		return
+	}
+	resp.Timings.Resolve = millis(time.Since(resolveStart))
+
+	if req.Format == formatJSON {
+		resp.Dependencies = make([]any, 0, len(pkgs))
+		for _, p := range pkgs {
+			dep, err := selectFields(newAPIDep(p), req.Fields)
+			if err != nil {
+				fail(err, http.StatusInternalServerError)
+				return
+			}
+			resp.Dependencies = append(resp.Dependencies, dep)
+		}
+	} else {
+		resp.Document, err = apiDocument(resp.Module, pkgs, req.Format)
+		if err != nil {
+			fail(err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp.Errors = errs
+	resp.Timings.Total = millis(time.Since(start))
+	writeAPI(w, resp, http.StatusOK)
+}
+
+// handleOpenAPI serves the OpenAPI document, GET /api/v1/openapi.json.
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fixedCacheControl)
+	_, _ = w.Write(openAPISpec)
+}
+
+// handleAPIv1NotFound reports unknown /api/v1 paths in the v1 envelope,
+// instead of falling through to the v0 routes.
+func handleAPIv1NotFound(w http.ResponseWriter, r *http.Request) {
+	resp := apiResponse{Errors: []apiError{{Message: fmt.Sprintf("%s %s: not found", r.Method, r.URL.Path)}}}
+	writeAPI(w, resp, http.StatusNotFound)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// loadSpec returns the parsed OpenAPI document.
+func loadSpec(t *testing.T) map[string]any {
+	t.Helper()
+
+	var spec map[string]any
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	return spec
+}
+
+// specRef returns the object at ref (e.g. "#/components/schemas/Request").
+func specRef(spec map[string]any, ref string) map[string]any {
+	v := any(spec)
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		m, _ := v.(map[string]any)
+		v = m[key]
+	}
+	m, _ := v.(map[string]any)
+	return m
+}
+
+// specResolve follows the $ref of obj, if any.
+func specResolve(spec, obj map[string]any) map[string]any {
+	if ref, ok := obj["$ref"].(string); ok {
+		return specResolve(spec, specRef(spec, ref))
+	}
+	return obj
+}
+
+// checkSchema validates v (decoded JSON) against an OpenAPI schema, it
+// supports the parts of the schema language used in openapi.json.
+func checkSchema(spec, schema map[string]any, v any, path string) error {
+	schema = specResolve(spec, schema)
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		n := 0
+		for _, s := range oneOf {
+			if checkSchema(spec, s.(map[string]any), v, path) == nil {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("%s: matches %d oneOf schemas", path, n)
+		}
+		return nil
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, v) {
+		return fmt.Errorf("%s: %v not in %v", path, v, enum)
+	}
+
+	switch typ := schema["type"]; typ {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", path, v)
+		}
+		required, _ := schema["required"].([]any)
+		for _, r := range required {
+			if _, ok := m[r.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %q", path, r)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for k, val := range m {
+			prop, ok := props[k].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unknown property %q", path, k)
+				}
+				continue
+			}
+			if err := checkSchema(spec, prop, val, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		a, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", path, v)
+		}
+		for i, val := range a {
+			if err := checkSchema(spec, schema["items"].(map[string]any), val, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: expected string, got %T", path, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected number, got %T", path, v)
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != math.Trunc(f) {
+			return fmt.Errorf("%s: expected integer, got %v", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", path, v)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %v", path, typ)
+	}
+	return nil
+}
+
+// specSchema returns the named component schema.
+func specSchema(spec map[string]any, name string) map[string]any {
+	return specRef(spec, "#/components/schemas/"+name)
+}
+
+// jsonNames returns the JSON field names of a struct type.
+func jsonNames(typ reflect.Type) []string {
+	var names []string
+	for f := range typ.Fields() {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// specNames returns the property names of a schema.
+func specNames(schema map[string]any) []string {
+	var names []string
+	for name := range schema["properties"].(map[string]any) {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// specEnum returns the enum of a schema as strings.
+func specEnum(schema map[string]any) []string {
+	var values []string
+	for _, v := range schema["enum"].([]any) {
+		values = append(values, v.(string))
+	}
+	return values
+}
+
+func TestOpenAPITypes(t *testing.T) {
+	spec := loadSpec(t)
+	if v, _ := spec["openapi"].(string); !strings.HasPrefix(v, "3.") {
+		t.Fatalf("expected OpenAPI 3, got %q", v)
+	}
+
+	types := map[string]reflect.Type{
+		"Request":    reflect.TypeFor[apiRequest](),
+		"Response":   reflect.TypeFor[apiResponse](),
+		"Dependency": reflect.TypeFor[apiDep](),
+		"Error":      reflect.TypeFor[apiError](),
+		"Timings":    reflect.TypeFor[apiTimings](),
+	}
+	for name, typ := range types {
+		schema := specSchema(spec, name)
+		if schema == nil {
+			t.Fatalf("%s: missing schema", name)
+		}
+		if got, expected := specNames(schema), jsonNames(typ); !slices.Equal(got, expected) {
+			t.Fatalf("%s: spec properties %v, %s fields %v", name, got, typ, expected)
+		}
+	}
+
+	fields := specSchema(spec, "Request")["properties"].(map[string]any)["fields"].(map[string]any)["items"].(map[string]any)
+	if got := specEnum(fields); !slices.Equal(got, apiFields) {
+		t.Fatalf("fields: spec %v, apiFields %v", got, apiFields)
+	}
+	if got := specNames(specSchema(spec, "Dependency")); !slices.Equal(got, slices.Sorted(slices.Values(apiFields))) {
+		t.Fatalf("Dependency: spec %v, apiFields %v", got, apiFields)
+	}
+	for _, schema := range []string{"Request", "Response"} {
+		format := specSchema(spec, schema)["properties"].(map[string]any)["format"].(map[string]any)
+		if got := specEnum(format); !slices.Equal(got, outputFormats) {
+			t.Fatalf("%s format: spec %v, outputFormats %v", schema, got, outputFormats)
+		}
+	}
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	mux := srv.routes()
+
+	for path, item := range spec["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			req := httptest.NewRequest(strings.ToUpper(method), path, strings.NewReader("{}"))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code == http.StatusNotFound || w.Code == http.StatusMethodNotAllowed {
+				t.Fatalf("%s %s: not routed (%d)", method, path, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("%s %s: expected JSON, got %q", method, path, ct)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if !bytes.Equal(w.Body.Bytes(), openAPISpec) {
+		t.Fatal("served spec differs from openapi.json")
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/deps", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"errors"`) {
+		t.Fatalf("expected v1 404, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+const testIndirectGoMod = `module example.com/test
+
+go 1.22
+
+require (
+	github.com/apple/a v1.2.3
+	example.org/x v1.0.0
+	github.com/cherry/c v1.0.0 // indirect
+)
+`
+
+var apiV1Cases = []struct {
+	name   string
+	body   string
+	status int
+	check  func(t *testing.T, resp apiResponse, raw map[string]any)
+}{
+	{
+		name:   "content",
+		body:   fmt.Sprintf(`{"content": %q}`, testIndirectGoMod),
+		status: http.StatusOK,
+		check: func(t *testing.T, resp apiResponse, raw map[string]any) {
+			if resp.Module != "example.com/test" || resp.Go != "1.22" || resp.Format != formatJSON {
+				t.Fatalf("bad metadata: %+v", resp)
+			}
+			deps := raw["dependencies"].([]any)
+			if len(deps) != 1 {
+				t.Fatalf("expected 1 direct resolved dependency, got %v", deps)
+			}
+			if desc := deps[0].(map[string]any)["description"]; desc != "desc A" {
+				t.Fatalf("expected description, got %v", desc)
+			}
+			if len(resp.Errors) != 1 || resp.Errors[0].Module != "example.org/x" {
+				t.Fatalf("expected error for example.org/x, got %+v", resp.Errors)
+			}
+		},
+	},
+	{
+		name:   "indirect",
+		body:   fmt.Sprintf(`{"content": %q, "indirect": true, "fields": ["name", "indirect"]}`, testIndirectGoMod),
+		status: http.StatusOK,
+		check: func(t *testing.T, resp apiResponse, raw map[string]any) {
+			deps := raw["dependencies"].([]any)
+			if len(deps) != 2 {
+				t.Fatalf("expected 2 dependencies, got %v", deps)
+			}
+			dep := deps[1].(map[string]any)
+			if len(dep) != 2 || dep["name"] != "github.com/cherry/c" || dep["indirect"] != true {
+				t.Fatalf("expected only name and indirect, got %v", dep)
+			}
+		},
+	},
+	{
+		name:   "repo",
+		body:   `{"repo": "owner/repo"}`,
+		status: http.StatusOK,
+		check: func(t *testing.T, resp apiResponse, raw map[string]any) {
+			if len(resp.Dependencies) != 2 || len(resp.Errors) != 0 {
+				t.Fatalf("expected 2 dependencies and no errors, got %v %v", resp.Dependencies, resp.Errors)
+			}
+		},
+	},
+	{
+		name:   "markdown",
+		body:   `{"repo": "owner/repo", "format": "markdown"}`,
+		status: http.StatusOK,
+		check: func(t *testing.T, resp apiResponse, raw map[string]any) {
+			doc, _ := raw["document"].(string)
+			if !strings.Contains(doc, "| [github.com/apple/a]") || raw["dependencies"] != nil {
+				t.Fatalf("expected markdown document, got %v", raw)
+			}
+		},
+	},
+	{
+		name:   "sbom",
+		body:   fmt.Sprintf(`{"content": %q, "format": "cyclonedx-json"}`, testIndirectGoMod),
+		status: http.StatusOK,
+		check: func(t *testing.T, resp apiResponse, raw map[string]any) {
+			doc, _ := raw["document"].(map[string]any)
+			if comps, _ := doc["components"].([]any); len(comps) != 3 {
+				t.Fatalf("expected every module in SBOM, got %v", doc)
+			}
+		},
+	},
+	{name: "both", body: fmt.Sprintf(`{"repo": "owner/repo", "content": %q}`, testGoMod), status: http.StatusBadRequest},
+	{name: "unknown option", body: `{"repo": "owner/repo", "sort": "name"}`, status: http.StatusBadRequest},
+	{name: "unknown field", body: `{"repo": "owner/repo", "fields": ["stars"]}`, status: http.StatusBadRequest},
+	{name: "fields format", body: `{"repo": "owner/repo", "fields": ["name"], "format": "markdown"}`, status: http.StatusBadRequest},
+	{name: "bad json", body: `repo=owner/repo`, status: http.StatusBadRequest},
+	{name: "missing go.mod", body: `{"repo": "owner/missing"}`, status: http.StatusBadGateway},
+}
+
+// TestHandleAPIv1 checks responses conform to the OpenAPI document.
+func TestHandleAPIv1(t *testing.T) {
+	spec := loadSpec(t)
+	op := spec["paths"].(map[string]any)["/api/v1/deps"].(map[string]any)["post"].(map[string]any)
+	responses := op["responses"].(map[string]any)
+
+	srv := setupRawGoMod(t, "HEAD")
+	srv.cache.Set("cherry/c", "desc C")
+	srv.cache.Set("example.org/x", "example.org/x")
+
+	for _, tc := range apiV1Cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.status == http.StatusOK {
+				var body any
+				if err := json.Unmarshal([]byte(tc.body), &body); err != nil {
+					t.Fatalf("request: %v", err)
+				}
+				if err := checkSchema(spec, specSchema(spec, "Request"), body, "request"); err != nil {
+					t.Fatalf("request doesn't match spec: %v", err)
+				}
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/deps", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			srv.routes().ServeHTTP(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("expected JSON, got %q", ct)
+			}
+
+			specResp, ok := responses[strconv.Itoa(w.Code)].(map[string]any)
+			if !ok {
+				t.Fatalf("status %d not in spec", w.Code)
+			}
+			schema := specResolve(spec, specResolve(spec, specResp)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any))
+
+			var raw map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if err := checkSchema(spec, schema, raw, "response"); err != nil {
+				t.Fatalf("response doesn't match spec: %v\n%s", err, w.Body.String())
+			}
+
+			var resp apiResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if tc.status != http.StatusOK {
+				if len(resp.Errors) != 1 || resp.Errors[0].Message == "" {
+					t.Fatalf("expected error message, got %+v", resp.Errors)
+				}
+				return
+			}
+			tc.check(t, resp, raw)
+		})
+	}
+}
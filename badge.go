@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// Badge metrics.
+const (
+	metricDeps     = "deps"
+	metricOutdated = "outdated"
+	metricArchived = "archived"
+	metricVulns    = "vulns"
+)
+
+var (
+	badgeMetrics        = []string{metricDeps, metricOutdated, metricArchived, metricVulns}
+	defaultBadgeMetrics = []string{metricDeps, metricOutdated, metricArchived}
+)
+
+// Badge colors, from shields.io.
+const (
+	colorGreen  = "#4c1"
+	colorYellow = "#dfb317"
+	colorOrange = "#fe7d37"
+	colorRed    = "#e05d44"
+	colorGrey   = "#9f9f9f"
+)
+
+// badgeErrorCacheControl lets failed badges be retried soon.
+const badgeErrorCacheControl = "public, max-age=60"
+
+// Badge is the text and color of a badge.
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// depStats are the badge metric values.
+type depStats map[string]int
+
+// repoArchived reports whether the GitHub repository is archived, using the
+// cache.
+func repoArchived(ctx context.Context, owner, repo string, cache repoCache) (bool, error) {
+	r, err := repoMeta(ctx, owner, repo, cache)
+	if err != nil {
+		return false, err
+	}
+	return r.Archived, nil
+}
+
+// moduleLatest returns the latest version of the module, using the cache.
+func moduleLatest(ctx context.Context, path string, cache repoCache) (string, error) {
+	key := "latest:" + path
+	if v, ok := cache.Get(key); ok {
+		return v, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	v, err := proxyLatest(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	cache.Set(key, v)
+	return v, nil
+}
+
+// badgeStats computes the metrics of the dependencies in data.
+func badgeStats(ctx context.Context, data []byte, metrics []string, cache repoCache) (depStats, error) {
+	if slices.Contains(metrics, metricVulns) && vulnDatabase == nil {
+		return nil, fmt.Errorf("%s: server doesn't check vulnerabilities", metricVulns)
+	}
+
+	pkgs, err := pkgsInfo(ctx, bytes.NewReader(data), cache)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := depStats{metricDeps: len(pkgs)}
+	for _, p := range pkgs {
+		if len(p.Vulns) > 0 {
+			stats[metricVulns]++
+		}
+
+		// Replaced modules are pinned on purpose.
+		if slices.Contains(metrics, metricOutdated) && p.Version != "" && p.Replace == "" {
+			latest, err := moduleLatest(ctx, p.Name, cache)
+			if err != nil {
+				slog.Warn("can't get latest version", "module", p.Name, "error", err)
+			} else if semver.Compare(latest, p.Version) > 0 {
+				stats[metricOutdated]++
+			}
+		}
+
+		if owner, repo := repoInfo(strings.TrimPrefix(p.URL, "https://")); slices.Contains(metrics, metricArchived) && owner != "" {
+			archived, err := repoArchived(ctx, owner, repo, cache)
+			if err != nil {
+				slog.Warn("can't check archived", "repo", p.URL, "error", err)
+			} else if archived {
+				stats[metricArchived]++
+			}
+		}
+	}
+	return stats, nil
+}
+
+// parseBadgeMetrics parses a comma separated metric list, "" is the default.
+func parseBadgeMetrics(s string) ([]string, error) {
+	if s == "" {
+		return defaultBadgeMetrics, nil
+	}
+
+	var metrics []string
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if !slices.Contains(badgeMetrics, m) {
+			return nil, fmt.Errorf("%q: unknown metric (valid: %s)", m, strings.Join(badgeMetrics, ", "))
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// newBadge returns the badge for stats, e.g. "deps | 24 | outdated: 3".
+// The first metric value is the message, the others are "name: value".
+func newBadge(label string, metrics []string, stats depStats) Badge {
+	if label == "" {
+		label = metrics[0]
+	}
+
+	parts := []string{strconv.Itoa(stats[metrics[0]])}
+	for _, m := range metrics[1:] {
+		parts = append(parts, fmt.Sprintf("%s: %d", m, stats[m]))
+	}
+
+	color := colorGreen
+	switch {
+	case slices.Contains(metrics, metricVulns) && stats[metricVulns] > 0:
+		color = colorRed
+	case slices.Contains(metrics, metricArchived) && stats[metricArchived] > 0:
+		color = colorOrange
+	case slices.Contains(metrics, metricOutdated) && stats[metricOutdated] > 0:
+		color = colorYellow
+	}
+
+	return Badge{Label: label, Message: strings.Join(parts, " | "), Color: color}
+}
+
+// textWidth approximates the width of 11px Verdana text.
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("ijlt|.:,;!' ", r):
+			width += 4
+		case r >= 'A' && r <= 'Z', strings.ContainsRune("mw", r):
+			width += 9
+		default:
+			width += 7
+		}
+	}
+	return width
+}
+
+var badgeTmpl = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+<title>{{.Label}}: {{.Message}}</title>
+<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/><rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/><rect width="{{.Width}}" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{.Message}}</text><text x="{{.MessageX}}" y="14">{{.Message}}</text>
+</g>
+</svg>
+`))
+
+// writeSVG writes the badge as a flat shields style SVG.
+func (b Badge) writeSVG(w http.ResponseWriter) error {
+	const padding = 10
+	lw, mw := textWidth(b.Label)+padding, textWidth(b.Message)+padding
+	data := struct {
+		Badge
+		Width, LabelWidth, MessageWidth int
+		LabelX, MessageX                float64
+	}{b, lw + mw, lw, mw, float64(lw) / 2, float64(lw) + float64(mw)/2}
+
+	var buf bytes.Buffer
+	if err := badgeTmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// shieldsColors maps badge colors to shields.io names.
+var shieldsColors = map[string]string{
+	colorGreen:  "brightgreen",
+	colorYellow: "yellow",
+	colorOrange: "orange",
+	colorRed:    "red",
+	colorGrey:   "lightgrey",
+}
+
+// shieldsJSON is the shields.io endpoint schema, see
+// https://shields.io/badges/endpoint-badge
+type shieldsJSON struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+	IsError       bool   `json:"isError,omitempty"`
+}
+
+// handleBadge serves the badge of a repository,
+// GET /badge/{owner}/{repo}[@ref].svg or .json (shields.io endpoint).
+// Query options are metric (comma separated, first is the message) and label.
+// Badges are computed at most badgeRateLimit times a minute per repository,
+// limited requests get the last badge, or a 429 if there's none.
+func (s *server) handleBadge(w http.ResponseWriter, r *http.Request) {
+	name, ext := r.PathValue("repo"), ""
+	for _, e := range []string{".svg", ".json"} {
+		if n, ok := strings.CutSuffix(name, e); ok {
+			name, ext = n, e
+		}
+	}
+	if ext == "" {
+		http.Error(w, "badge should end with .svg or .json", http.StatusNotFound)
+		return
+	}
+
+	repo, query := r.PathValue("owner")+"/"+name, r.URL.Query()
+	key := "badge:" + repo + "?" + query.Encode()
+	var badge Badge
+	var cacheControl string
+	if ok, wait := s.allowBadge(repo); ok {
+		badge, cacheControl = s.badge(r.Context(), repo, query)
+		if badge.Color != colorGrey {
+			if data, err := json.Marshal(badge); err == nil {
+				s.cache.Set(key, string(data))
+			}
+		}
+	} else if data, ok := s.cache.Get(key); ok && json.Unmarshal([]byte(data), &badge) == nil {
+		cacheControl = headCacheControl
+	} else {
+		tooManyRequests(w, wait, "badge", repo)
+		return
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if ext == ".json" {
+		w.Header().Set("Content-Type", "application/json")
+		reply := shieldsJSON{
+			SchemaVersion: 1,
+			Label:         badge.Label,
+			Message:       badge.Message,
+			Color:         shieldsColors[badge.Color],
+			IsError:       badge.Color == colorGrey,
+		}
+		if err := json.NewEncoder(w).Encode(reply); err != nil {
+			slog.Error("encode badge", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if err := badge.writeSVG(w); err != nil {
+		slog.Error("render badge", "error", err)
+	}
+}
+
+// allowBadge takes a token from the bucket of repo (owner/repo[@ref]), all
+// refs of a repository share it.
+func (s *server) allowBadge(repo string) (bool, time.Duration) {
+	if s.badges == nil {
+		return true, 0
+	}
+	name, _, _ := strings.Cut(repo, "@")
+	return s.badges.allow(strings.ToLower(name), time.Now())
+}
+
+// badge returns the badge of repo (owner/repo[@ref]) and its Cache-Control.
+// Errors are shown in the badge, so READMEs don't show a broken image.
+func (s *server) badge(ctx context.Context, repo string, query url.Values) (Badge, string) {
+	label := query.Get("label")
+	fail := func(err error) (Badge, string) {
+		slog.Warn("badge", "repo", repo, "error", err)
+		if label == "" {
+			label = metricDeps
+		}
+		return Badge{Label: label, Message: "error", Color: colorGrey}, badgeErrorCacheControl
+	}
+
+	metrics, err := parseBadgeMetrics(query.Get("metric"))
+	if err != nil {
+		return fail(err)
+	}
+
+	name, ref, err := parseRepoRef(repo)
+	if err != nil {
+		return fail(err)
+	}
+	data, err := repoGoMod(ctx, name, ref)
+	if err != nil {
+		return fail(err)
+	}
+	if err := checkModules(data); err != nil {
+		return fail(err)
+	}
+
+	stats, err := badgeStats(ctx, data, metrics, s.cache)
+	if err != nil {
+		return fail(err)
+	}
+	return newBadge(label, metrics, stats), repoCacheControl(ref)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var badgeMetricsCases = []struct {
+	in      string
+	metrics []string
+	err     bool
+}{
+	{"", defaultBadgeMetrics, false},
+	{"outdated", []string{"outdated"}, false},
+	{"vulns, deps", []string{"vulns", "deps"}, false},
+	{"deps,stars", nil, true},
+}
+
+func Test_parseBadgeMetrics(t *testing.T) {
+	for _, tc := range badgeMetricsCases {
+		t.Run(tc.in, func(t *testing.T) {
+			metrics, err := parseBadgeMetrics(tc.in)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseBadgeMetrics: %v", err)
+			}
+			if strings.Join(metrics, ",") != strings.Join(tc.metrics, ",") {
+				t.Fatalf("expected %v, got %v", tc.metrics, metrics)
+			}
+		})
+	}
+}
+
+var newBadgeCases = []struct {
+	name    string
+	label   string
+	metrics []string
+	stats   depStats
+	badge   Badge
+}{
+	{"clean", "", []string{"deps", "outdated"}, depStats{"deps": 7}, Badge{"deps", "7 | outdated: 0", colorGreen}},
+	{"outdated", "", []string{"outdated"}, depStats{"deps": 7, "outdated": 2}, Badge{"outdated", "2", colorYellow}},
+	{"archived", "dependencies", defaultBadgeMetrics, depStats{"deps": 7, "outdated": 2, "archived": 1}, Badge{"dependencies", "7 | outdated: 2 | archived: 1", colorOrange}},
+	{"vulns", "", []string{"vulns", "archived"}, depStats{"vulns": 1, "archived": 1}, Badge{"vulns", "1 | archived: 1", colorRed}},
+	// Colors only follow metrics shown in the badge.
+	{"hidden", "", []string{"deps"}, depStats{"deps": 7, "vulns": 1}, Badge{"deps", "7", colorGreen}},
+}
+
+func Test_newBadge(t *testing.T) {
+	for _, tc := range newBadgeCases {
+		t.Run(tc.name, func(t *testing.T) {
+			badge := newBadge(tc.label, tc.metrics, tc.stats)
+			if badge != tc.badge {
+				t.Fatalf("expected %+v, got %+v", tc.badge, badge)
+			}
+		})
+	}
+}
+
+func Test_textWidth(t *testing.T) {
+	if w := textWidth("deps"); w != 28 {
+		t.Fatalf("deps: expected 28, got %d", w)
+	}
+	if textWidth("Mw") <= textWidth("il") {
+		t.Fatal("expected wide letters to be wider")
+	}
+}
+
+// setupBadge serves testGoMod with apple/a outdated and banana/b archived.
+func setupBadge(t *testing.T) *server {
+	t.Helper()
+
+	srv := setupRawGoMod(t, "HEAD")
+	srv.cache.Set("latest:github.com/apple/a", "v1.3.0")
+	srv.cache.Set("latest:github.com/banana/b", "v1.0.0")
+	for repo, archived := range map[string]bool{"apple/a": false, "banana/b": true} {
+		data, err := json.Marshal(repoEntry{Repo: githubRepo{Archived: archived}, Fetched: time.Now()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv.cache.Set("repo:"+repo, string(data))
+	}
+	return srv
+}
+
+func TestHandleBadgeSVG(t *testing.T) {
+	srv := setupBadge(t)
+
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/badge/owner/repo.svg", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d\n%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+		t.Fatalf("expected Content-Type image/svg+xml, got %q", ct)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != headCacheControl {
+		t.Fatalf("expected Cache-Control %q, got %q", headCacheControl, cc)
+	}
+	body := w.Body.String()
+	for _, s := range []string{"<svg", "2 | outdated: 1 | archived: 1", colorOrange} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("expected %q in badge:\n%s", s, body)
+		}
+	}
+}
+
+func TestHandleBadgeJSON(t *testing.T) {
+	srv := setupBadge(t)
+
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/badge/owner/repo.json?metric=outdated&label=stale", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d\n%s", w.Code, w.Body.String())
+	}
+	var reply shieldsJSON
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	expected := shieldsJSON{SchemaVersion: 1, Label: "stale", Message: "1", Color: "yellow"}
+	if reply != expected {
+		t.Fatalf("expected %+v, got %+v", expected, reply)
+	}
+}
+
+func TestHandleBadgeError(t *testing.T) {
+	srv := setupBadge(t)
+
+	for _, path := range []string{"/badge/owner/repo.json?metric=stars", "/badge/owner/missing.json"} {
+		w := httptest.NewRecorder()
+		srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status: %d", path, w.Code)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != badgeErrorCacheControl {
+			t.Fatalf("%s: expected Cache-Control %q, got %q", path, badgeErrorCacheControl, cc)
+		}
+		var reply shieldsJSON
+		if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+			t.Fatalf("%s: decode: %v", path, err)
+		}
+		if !reply.IsError || reply.Message != "error" {
+			t.Fatalf("%s: expected error badge, got %+v", path, reply)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/badge/owner/repo.png", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown format, got %d", w.Code)
+	}
+}
+
+func TestHandleBadgeRateLimit(t *testing.T) {
+	srv := setupBadge(t)
+	srv.badges = newRateLimiter(1, 1)
+	h := srv.routes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/badge/owner/repo.svg"); w.Code != http.StatusOK {
+		t.Fatalf("status: %d\n%s", w.Code, w.Body.String())
+	}
+
+	// Limited badges are served from the last result, without fetching go.mod.
+	githubRawBase = "http://127.0.0.1:0"
+	w := get("/badge/owner/repo.json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("cached: status: %d\n%s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "2 | outdated: 1 | archived: 1") {
+		t.Fatalf("expected cached badge, got %s", w.Body.String())
+	}
+
+	for _, path := range []string{"/badge/owner/repo.svg?metric=vulns", "/badge/Owner/Repo@v1.0.0.svg"} {
+		w := get(path)
+		if w.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: expected 429, got %d", path, w.Code)
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Fatalf("%s: missing Retry-After", path)
+		}
+	}
+}
+
+func TestHandleBadgeVulns(t *testing.T) {
+	prevCheck, prevPath := checkVulns, vulnDBPath
+	checkVulns, vulnDBPath = true, "testdata/vulndb"
+	t.Cleanup(func() { checkVulns, vulnDBPath, vulnDatabase = prevCheck, prevPath, nil })
+	if err := setupChecks(); err != nil {
+		t.Fatalf("setupChecks: %v", err)
+	}
+	srv := setupBadge(t)
+
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/badge/owner/repo.json?metric=vulns", nil))
+
+	var reply shieldsJSON
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	expected := shieldsJSON{SchemaVersion: 1, Label: "vulns", Message: "1", Color: "red"}
+	if reply != expected {
+		t.Fatalf("expected %+v, got %+v", expected, reply)
+	}
+
+	w = httptest.NewRecorder()
+	body := `{"repo": "owner/repo", "fields": ["name", "vulns"]}`
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/deps", strings.NewReader(body)))
+	if !strings.Contains(w.Body.String(), "GO-2099-0001") {
+		t.Fatalf("expected vulnerability in API response:\n%s", w.Body.String())
+	}
+}
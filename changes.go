@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Release is a release (or tag) of a dependency.
+type Release struct {
+	Version string
+	Title   string `json:",omitempty"`
+	Body    string `json:",omitempty"`
+	URL     string `json:",omitempty"`
+}
+
+// Changes are the changes of a dependency between two versions.
+type Changes struct {
+	From, To string
+	Releases []Release `json:",omitempty"` // newest first
+	// Changelog are the CHANGELOG.md lines added between From and To, used
+	// when there are no releases or tags.
+	Changelog string `json:",omitempty"`
+}
+
+var showChanges bool
+
+// maxGitHubPages is the maximal number of pages read from GitHub list APIs.
+const maxGitHubPages = 5
+
+// githubGet decodes the JSON reply of GitHub API path to v.
+func githubGet(ctx context.Context, path string, v any) error {
+	url := githubAPIBase + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	if token := os.Getenv(tokenKey); token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%q: %s", url, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%q: can't decode JSON - %w", url, err)
+	}
+	return nil
+}
+
+// tagVersion returns the module version of a git tag in the module sub
+// directory dir, or "" if the tag is not a version of the module.
+func tagVersion(tag, dir string) string {
+	if dir != "" {
+		var ok bool
+		if tag, ok = strings.CutPrefix(tag, dir+"/"); !ok {
+			return ""
+		}
+	}
+	if !semver.IsValid(tag) {
+		return ""
+	}
+	return tag
+}
+
+// inRange reports whether from < version <= to.
+func inRange(version, from, to string) bool {
+	return semver.Compare(version, from) > 0 && semver.Compare(version, to) <= 0
+}
+
+// githubReleases returns the releases and tags of the module in dir between
+// from and to, newest first.
+// Tags without a release have no Title or Body.
+func githubReleases(ctx context.Context, owner, repo, dir, from, to string) ([]Release, error) {
+	repoPath := fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo))
+	byVersion := make(map[string]Release)
+
+	for page := 1; page <= maxGitHubPages; page++ {
+		var releases []struct {
+			TagName string `json:"tag_name"`
+			Name    string
+			Body    string
+			HTMLURL string `json:"html_url"`
+			Draft   bool
+		}
+		if err := githubGet(ctx, fmt.Sprintf("%s/releases?per_page=100&page=%d", repoPath, page), &releases); err != nil {
+			return nil, err
+		}
+
+		for _, r := range releases {
+			v := tagVersion(r.TagName, dir)
+			if r.Draft || v == "" || !inRange(v, from, to) {
+				continue
+			}
+			byVersion[v] = Release{Version: v, Title: r.Name, Body: strings.TrimSpace(r.Body), URL: r.HTMLURL}
+		}
+		if len(releases) < 100 {
+			break
+		}
+	}
+
+	for page := 1; page <= maxGitHubPages; page++ {
+		var tags []struct {
+			Name string
+		}
+		if err := githubGet(ctx, fmt.Sprintf("%s/tags?per_page=100&page=%d", repoPath, page), &tags); err != nil {
+			return nil, err
+		}
+
+		for _, t := range tags {
+			v := tagVersion(t.Name, dir)
+			if v == "" || !inRange(v, from, to) {
+				continue
+			}
+			if _, ok := byVersion[v]; !ok {
+				byVersion[v] = Release{Version: v, URL: fmt.Sprintf("https://github.com/%s/%s/tree/%s", owner, repo, t.Name)}
+			}
+		}
+		if len(tags) < 100 {
+			break
+		}
+	}
+
+	releases := make([]Release, 0, len(byVersion))
+	for _, r := range byVersion {
+		releases = append(releases, r)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return semver.Compare(releases[i].Version, releases[j].Version) > 0
+	})
+	return releases, nil
+}
+
+// rawFile returns a file from the GitHub raw host.
+func rawFile(ctx context.Context, owner, repo, ref, file string) (string, error) {
+	rawURL := fmt.Sprintf("%s/%s/%s/%s/%s", githubRawBase, url.PathEscape(owner), url.PathEscape(repo), ref, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%q: %s", rawURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLicenseSize))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// changelogDiff returns the lines of newer which are not in older, changelogs
+// usually add entries at the top.
+func changelogDiff(older, newer string) string {
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(older, "\n") {
+		seen[strings.TrimSpace(line)] = true
+	}
+
+	var added []string
+	for _, line := range strings.Split(newer, "\n") {
+		if !seen[strings.TrimSpace(line)] {
+			added = append(added, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(added, "\n"))
+}
+
+// moduleChanges returns the changes of the module between its version and to.
+// repoPath is the GitHub repository (e.g. github.com/tebeka/expmod).
+func moduleChanges(ctx context.Context, mod module.Version, to, repoPath string) (*Changes, error) {
+	owner, repo := repoInfo(repoPath)
+	if owner == "" || repo == "" {
+		return nil, fmt.Errorf("%s: not a GitHub repository", mod.Path)
+	}
+
+	fromRef, dir := gitRef(repoPath, mod)
+	toRef, _ := gitRef(repoPath, module.Version{Path: mod.Path, Version: to})
+	from := strings.TrimSuffix(mod.Version, "+incompatible")
+	c := Changes{From: mod.Version, To: to}
+
+	releases, err := githubReleases(ctx, owner, repo, dir, from, strings.TrimSuffix(to, "+incompatible"))
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) > 0 {
+		c.Releases = releases
+		return &c, nil
+	}
+
+	changelog := path.Join(dir, "CHANGELOG.md")
+	newer, err := rawFile(ctx, owner, repo, toRef, changelog)
+	if err != nil {
+		return nil, fmt.Errorf("%s: no releases, tags or changelog - %w", mod.Path, err)
+	}
+	older, err := rawFile(ctx, owner, repo, fromRef, changelog)
+	if err != nil {
+		older = "" // changelog added after mod.Version
+	}
+	c.Changelog = changelogDiff(older, newer)
+	return &c, nil
+}
+
+// depChanges returns the changes between the pinned version of mod and to,
+// the latest version if to is "". It's nil if mod is up to date.
+// Changes between two versions are cached.
+func depChanges(ctx context.Context, mod module.Version, to, repoPath string, cache repoCache) (*Changes, error) {
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+
+	if to == "" {
+		var err error
+		if to, err = proxyLatest(ctx, mod.Path); err != nil {
+			return nil, err
+		}
+	}
+	if semver.Compare(to, mod.Version) <= 0 {
+		return nil, nil
+	}
+
+	key := fmt.Sprintf("changes:%s..%s", mod, to)
+	if data, ok := cache.Get(key); ok {
+		var c Changes
+		if err := json.Unmarshal([]byte(data), &c); err == nil {
+			return &c, nil
+		}
+	}
+
+	c, err := moduleChanges(ctx, mod, to, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(c); err == nil {
+		cache.Set(key, string(data))
+	}
+	return c, nil
+}
+
+// parseChangesArg parses the argument of the changes command:
+// module[@version], the version is taken from goMod if missing.
+func parseChangesArg(arg string, goMod []byte) (module.Version, error) {
+	path, version, ok := strings.Cut(arg, "@")
+	if ok {
+		return module.Version{Path: path, Version: version}, nil
+	}
+
+	if goMod == nil {
+		return module.Version{}, fmt.Errorf("%s: no version and no go.mod", path)
+	}
+	f, err := modfile.ParseLax("go.mod", goMod, nil)
+	if err != nil {
+		return module.Version{}, err
+	}
+	for _, r := range f.Require {
+		if r.Mod.Path == path {
+			return r.Mod, nil
+		}
+	}
+	return module.Version{}, fmt.Errorf("%s: not found in go.mod", path)
+}
+
+// runChanges prints the changes of the module in arg to version to, the
+// latest version if to is "".
+func runChanges(ctx context.Context, w io.Writer, arg, to string, cache repoCache) error {
+	switch outputFormat {
+	case formatText, formatMarkdown, formatJSON:
+	default:
+		return fmt.Errorf("changes: unsupported format %q (valid: %s, %s, %s)", outputFormat, formatText, formatMarkdown, formatJSON)
+	}
+
+	goMod, err := os.ReadFile("go.mod")
+	if err != nil {
+		goMod = nil
+	}
+
+	mod, err := parseChangesArg(arg, goMod)
+	if err != nil {
+		return err
+	}
+	if to != "" && !semver.IsValid(to) {
+		return fmt.Errorf("%q: bad version", to)
+	}
+
+	info, _ := depInfo(ctx, mod.Path, mod.Version, cache)
+	c, err := depChanges(ctx, mod, to, strings.TrimPrefix(info.URL, "https://"), cache)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		c = &Changes{From: mod.Version, To: mod.Version}
+	}
+
+	switch outputFormat {
+	case formatText:
+		if c.From == c.To {
+			fmt.Fprintf(w, "%s %s is up to date\n", mod.Path, c.From)
+			return nil
+		}
+		fmt.Fprintf(w, "%s %s -> %s:\n", mod.Path, c.From, c.To)
+		displayChanges(w, c, "\t", true)
+	case formatMarkdown:
+		writeChangesMarkdown(w, mod.Path, c, "#")
+	case formatJSON:
+		return writeJSON(w, c)
+	}
+	return nil
+}
+
+// displayChanges writes the releases in c as text lines prefixed by indent,
+// release bodies only if full.
+func displayChanges(w io.Writer, c *Changes, indent string, full bool) {
+	for _, r := range c.Releases {
+		title := r.Version
+		if r.Title != "" && r.Title != r.Version {
+			title += ": " + r.Title
+		}
+		fmt.Fprintf(w, "%s%s\n", indent, title)
+		if full && r.Body != "" {
+			for _, line := range strings.Split(r.Body, "\n") {
+				fmt.Fprintf(w, "%s\t%s\n", indent, strings.TrimRight(line, "\r "))
+			}
+		}
+	}
+	if c.Changelog != "" {
+		fmt.Fprintf(w, "%sCHANGELOG.md\n", indent)
+		if full {
+			for _, line := range strings.Split(c.Changelog, "\n") {
+				fmt.Fprintf(w, "%s\t%s\n", indent, line)
+			}
+		}
+	}
+}
+
+// writeChangesMarkdown writes the changes as markdown, heading is the level
+// of the top heading (e.g. "##").
+func writeChangesMarkdown(w io.Writer, name string, c *Changes, heading string) {
+	if c.From == c.To {
+		fmt.Fprintf(w, "%s %s\n\n`%s` is up to date.\n\n", heading, name, c.From)
+		return
+	}
+
+	fmt.Fprintf(w, "%s %s `%s` → `%s`\n\n", heading, name, c.From, c.To)
+	for _, r := range c.Releases {
+		title := r.Version
+		if r.Title != "" && r.Title != r.Version {
+			title += " - " + r.Title
+		}
+		if r.URL != "" {
+			title = fmt.Sprintf("[%s](%s)", title, r.URL)
+		}
+		fmt.Fprintf(w, "%s# %s\n\n", heading, title)
+		if r.Body != "" {
+			fmt.Fprintf(w, "%s\n\n", r.Body)
+		}
+	}
+	if c.Changelog != "" {
+		fmt.Fprintf(w, "%s# CHANGELOG.md\n\n%s\n\n", heading, c.Changelog)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+func Test_moduleChangesReleases(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/apple/a/releases":
+			_, _ = w.Write([]byte(`[
+				{"tag_name": "v1.4.0", "name": "Faster", "body": "Speed up\r\n", "html_url": "https://github.com/apple/a/releases/v1.4.0"},
+				{"tag_name": "v1.5.0", "name": "Next", "draft": true},
+				{"tag_name": "v1.2.3", "name": "Pinned"}
+			]`))
+		case "/repos/apple/a/tags":
+			_, _ = w.Write([]byte(`[{"name": "v1.4.0"}, {"name": "v1.3.0"}, {"name": "v1.2.3"}, {"name": "sub/v1.3.5"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer restore()
+
+	mod := module.Version{Path: "github.com/apple/a", Version: "v1.2.3"}
+	c, err := moduleChanges(context.Background(), mod, "v1.4.0", "github.com/apple/a")
+	if err != nil {
+		t.Fatalf("moduleChanges: %v", err)
+	}
+
+	if len(c.Releases) != 2 {
+		t.Fatalf("expected 2 releases, got %+v", c.Releases)
+	}
+	if r := c.Releases[0]; r.Version != "v1.4.0" || r.Title != "Faster" || r.Body != "Speed up" {
+		t.Fatalf("bad release: %+v", r)
+	}
+	if r := c.Releases[1]; r.Version != "v1.3.0" || r.Title != "" || r.URL != "https://github.com/apple/a/tree/v1.3.0" {
+		t.Fatalf("bad tag: %+v", r)
+	}
+
+	var buf bytes.Buffer
+	writeChangesMarkdown(&buf, mod.Path, c, "#")
+	if !strings.Contains(buf.String(), "## [v1.4.0 - Faster](https://github.com/apple/a/releases/v1.4.0)") {
+		t.Fatalf("bad markdown:\n%s", buf.String())
+	}
+}
+
+func Test_moduleChangesChangelog(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/apple/a/releases", "/repos/apple/a/tags":
+			_, _ = w.Write([]byte(`[]`))
+		case "/apple/a/sub/v1.1.0/sub/CHANGELOG.md":
+			_, _ = w.Write([]byte("# Changes\n\n## v1.1.0\n- Fix bug\n\n## v1.0.0\n- Initial\n"))
+		case "/apple/a/sub/v1.0.0/sub/CHANGELOG.md":
+			_, _ = w.Write([]byte("# Changes\n\n## v1.0.0\n- Initial\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer restore()
+
+	mod := module.Version{Path: "github.com/apple/a/sub", Version: "v1.0.0"}
+	c, err := moduleChanges(context.Background(), mod, "v1.1.0", "github.com/apple/a")
+	if err != nil {
+		t.Fatalf("moduleChanges: %v", err)
+	}
+
+	expected := "## v1.1.0\n- Fix bug"
+	if c.Changelog != expected {
+		t.Fatalf("expected %q, got %q", expected, c.Changelog)
+	}
+}
+
+func Test_parseChangesArg(t *testing.T) {
+	goMod := []byte("module example.com/m\n\nrequire github.com/apple/a v1.2.3\n")
+
+	mod, err := parseChangesArg("github.com/apple/a", goMod)
+	if err != nil || mod.Version != "v1.2.3" {
+		t.Fatalf("from go.mod: got %v, %v", mod, err)
+	}
+
+	mod, err = parseChangesArg("github.com/banana/b@v0.1.0", nil)
+	if err != nil || mod.Version != "v0.1.0" {
+		t.Fatalf("explicit version: got %v, %v", mod, err)
+	}
+
+	if _, err := parseChangesArg("github.com/banana/b", goMod); err == nil {
+		t.Fatalf("expected error for missing module")
+	}
+}
+
+func Test_depChangesCache(t *testing.T) {
+	calls := 0
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/repos/apple/a/releases":
+			_, _ = w.Write([]byte(`[{"tag_name": "v1.4.0", "name": "Faster"}, {"tag_name": "v1.3.0", "name": "Better"}]`))
+		case "/repos/apple/a/tags":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer restore()
+
+	cache := mapCache{m: make(map[string]string)}
+	mod := module.Version{Path: "github.com/apple/a", Version: "v1.2.3"}
+	for range 2 {
+		c, err := depChanges(t.Context(), mod, "v1.3.0", "github.com/apple/a", cache)
+		if err != nil {
+			t.Fatalf("depChanges: %v", err)
+		}
+		if c.To != "v1.3.0" || len(c.Releases) != 1 || c.Releases[0].Title != "Better" {
+			t.Fatalf("unexpected changes: %+v", c)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 GitHub calls (releases and tags), got %d", calls)
+	}
+
+	c, err := depChanges(t.Context(), mod, "v1.2.0", "github.com/apple/a", cache)
+	if err != nil || c != nil {
+		t.Fatalf("older target: expected no changes, got %+v, %v", c, err)
+	}
+}
+
+func Test_writeMarkdownAnnotations(t *testing.T) {
+	pkgs := []PkgInfo{
+		{
+			Name: "github.com/apple/a", Version: "v1.2.3",
+			Deprecated: &Deprecation{Message: "use github.com/apple/b", Replacement: "github.com/apple/b"},
+			Retracted:  &Retraction{Latest: "v1.3.0"},
+		},
+	}
+
+	var buf bytes.Buffer
+	writeMarkdown(&buf, "example.com/test", pkgs)
+	for _, s := range []string{
+		"**DEPRECATED** use github.com/apple/b (use `github.com/apple/b`)",
+		"**RETRACTED** no rationale given (latest v1.3.0)",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatalf("expected %q in:\n%s", s, buf.String())
+		}
+	}
+}
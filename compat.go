@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"go/version"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// GoCompat is the Go version a dependency requires, at the pinned and at the
+// latest version.
+type GoCompat struct {
+	Go        string `json:",omitempty"` // go directive
+	Toolchain string `json:",omitempty"` // toolchain directive
+
+	Latest          string `json:",omitempty"`
+	LatestGo        string `json:",omitempty"`
+	LatestToolchain string `json:",omitempty"`
+
+	// TooNew is set if the pinned version needs a newer Go than the main module.
+	TooNew bool `json:",omitempty"`
+	// LatestTooNew is set if upgrading to the latest version forces a go bump.
+	LatestTooNew bool `json:",omitempty"`
+}
+
+var checkGoCompat bool
+
+// goDirectives returns the go and toolchain directives in a go.mod.
+func goDirectives(name string, data []byte) (string, string, error) {
+	// ParseLax ignores toolchain, use it only for go.mod files Parse rejects.
+	f, err := modfile.Parse(name, data, nil)
+	if err != nil {
+		if f, err = modfile.ParseLax(name, data, nil); err != nil {
+			return "", "", err
+		}
+	}
+
+	var goVersion, toolchain string
+	if f.Go != nil {
+		goVersion = f.Go.Version
+	}
+	if f.Toolchain != nil {
+		toolchain = f.Toolchain.Name
+	}
+	return goVersion, toolchain, nil
+}
+
+// newerGo reports whether Go version need is newer than have.
+// An empty have (unknown main module version) is never too old.
+func newerGo(need, have string) bool {
+	if need == "" || have == "" {
+		return false
+	}
+	return version.Compare(goRelease(need), goRelease(have)) > 0
+}
+
+// goRelease converts a go directive to a toolchain name, a bare language
+// version (1.22) is its first release (go1.22.0).
+func goRelease(v string) string {
+	v = "go" + v
+	if version.Lang(v) == v {
+		v += ".0"
+	}
+	return v
+}
+
+// mainGoVersion returns the go version of the main module in data, or "".
+func mainGoVersion(data []byte) string {
+	format := inputFormat
+	if format == inputAuto {
+		format = detectInputFormat(data)
+	}
+
+	switch format {
+	case inputGoList:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var m listModule
+			if err := dec.Decode(&m); err != nil {
+				return ""
+			}
+			if m.Main {
+				return m.GoVersion
+			}
+		}
+	case inputGraph:
+		// The main module lists its go version as a "go@1.22" edge.
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			fields := strings.Fields(s.Text())
+			if len(fields) != 2 || strings.Contains(fields[0], "@") {
+				continue
+			}
+			if v, ok := strings.CutPrefix(fields[1], "go@"); ok {
+				return v
+			}
+		}
+		return ""
+	}
+
+	goVersion, _, err := goDirectives("go.mod", data)
+	if err != nil {
+		return ""
+	}
+	return goVersion
+}
+
+// goCompat returns the Go requirements of mod, pinned and latest, compared to
+// mainGo.
+func goCompat(ctx context.Context, mod module.Version, mainGo string, cache repoCache) (*GoCompat, error) {
+	data, err := modData(ctx, mod, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	var c GoCompat
+	c.Go, c.Toolchain, err = goDirectives(mod.String(), data)
+	if err != nil {
+		return nil, err
+	}
+	c.TooNew = newerGo(c.Go, mainGo)
+
+	lctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	latest, err := proxyLatest(lctx, mod.Path)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	c.Latest = latest
+
+	if latest == mod.Version {
+		c.LatestGo, c.LatestToolchain, c.LatestTooNew = c.Go, c.Toolchain, c.TooNew
+		return &c, nil
+	}
+
+	latestMod := module.Version{Path: mod.Path, Version: latest}
+	data, err = modData(ctx, latestMod, cache)
+	if err != nil {
+		return nil, err
+	}
+	c.LatestGo, c.LatestToolchain, err = goDirectives(latestMod.String(), data)
+	if err != nil {
+		return nil, err
+	}
+	c.LatestTooNew = newerGo(c.LatestGo, mainGo)
+
+	return &c, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+func Test_mainGoVersion(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"gomod", "module example.com/m\n\ngo 1.22\n\ntoolchain go1.22.3\n", "1.22"},
+		{"golist", `{"Path": "example.com/m", "Main": true, "GoVersion": "1.21"}`, "1.21"},
+		{"graph", "example.com/m github.com/apple/a@v1.2.3\nexample.com/m go@1.23.1\n", "1.23.1"},
+		{"none", "module example.com/m\n", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := mainGoVersion([]byte(tc.data))
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_goCompat(t *testing.T) {
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@latest":       `{"Version": "v1.4.0"}`,
+		"/github.com/apple/a/@v/v1.2.3.mod": "module github.com/apple/a\n\ngo 1.21\n",
+		"/github.com/apple/a/@v/v1.4.0.mod": "module github.com/apple/a\n\ngo 1.23.0\n\ntoolchain go1.23.4\n",
+	})
+	cache := mapCache{m: make(map[string]string)}
+
+	c, err := goCompat(t.Context(), module.Version{Path: "github.com/apple/a", Version: "v1.2.3"}, "1.22", cache)
+	if err != nil {
+		t.Fatalf("goCompat: %v", err)
+	}
+
+	expected := GoCompat{
+		Go:              "1.21",
+		Latest:          "v1.4.0",
+		LatestGo:        "1.23.0",
+		LatestToolchain: "go1.23.4",
+		LatestTooNew:    true,
+	}
+	if *c != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *c)
+	}
+}
+
+func Test_newerGo(t *testing.T) {
+	cases := []struct {
+		need, have string
+		want       bool
+	}{
+		{"1.22", "1.21", true},
+		{"1.21.5", "1.21", true},
+		{"1.21", "1.22", false},
+		{"1.22.0", "1.22", false},
+		{"1.22rc1", "1.22", false},
+		{"1.22", "", false},
+	}
+
+	for _, tc := range cases {
+		if got := newerGo(tc.need, tc.have); got != tc.want {
+			t.Errorf("%q > %q: expected %v, got %v", tc.need, tc.have, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Web server options.
+var (
+	configFile      string
+	readTimeout     = 5 * time.Second
+	writeTimeout    = 2 * time.Minute
+	idleTimeout     = 2 * time.Minute
+	shutdownTimeout = 10 * time.Second
+	maxFormBytes    = int64(2 << 20)
+	logLevel        = slog.LevelInfo
+	tlsCert         string
+	tlsKey          string
+)
+
+const (
+	configEnvKey = "EXPMOD_CONFIG"
+	envPrefix    = "EXPMOD_"
+)
+
+// serverFlags are the flags which can also be set from the environment or the
+// config file. -serve is not one of them, the server starts only when it's on
+// the command line.
+var serverFlags = []string{
+	"read-timeout", "write-timeout", "idle-timeout", "shutdown-timeout",
+	"cache-size", "cache-ttl", "cache-file", "warmup",
+	"rate-limit", "rate-burst", "badge-rate-limit", "trusted-proxies", "max-modules",
+	"max-form-bytes", "log-level",
+	"tls-cert", "tls-key",
+}
+
+// flagEnv returns the environment variable of a flag, e.g. EXPMOD_CACHE_SIZE
+// for cache-size.
+func flagEnv(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// readConfig reads "name = value" lines, empty lines and lines starting with
+// # are ignored.
+func readConfig(r io.Reader) (map[string]string, error) {
+	cfg := make(map[string]string)
+	s := bufio.NewScanner(r)
+	for lnum := 1; s.Scan(); lnum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: missing '=' in %q", lnum, line)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !slices.Contains(serverFlags, name) {
+			return nil, fmt.Errorf("%d: unknown setting %q", lnum, name)
+		}
+		cfg[name] = strings.Trim(value, `"`)
+	}
+	return cfg, s.Err()
+}
+
+// applyConfig sets the server flags which are not set on the command line,
+// from the environment and then from the config file (may be "").
+// PORT, set by hosting platforms, overrides the port of -serve, it's ignored
+// without -serve.
+func applyConfig(fs *flag.FlagSet, fileName string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	cfg := make(map[string]string)
+	if fileName != "" {
+		file, err := os.Open(fileName) // #nosec G304
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		cfg, err = readConfig(file)
+		if err != nil {
+			return fmt.Errorf("%q:%w", fileName, err)
+		}
+	}
+
+	for _, name := range serverFlags {
+		if set[name] || fs.Lookup(name) == nil {
+			continue
+		}
+
+		value, ok := os.LookupEnv(flagEnv(name))
+		source := flagEnv(name)
+		if !ok {
+			value, ok = cfg[name]
+			source = fileName
+		}
+		if !ok {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s: %s: %w", source, name, err)
+		}
+	}
+
+	if _, ok := os.LookupEnv(flagEnv("serve")); ok {
+		slog.Warn("ignoring environment, use -serve to start the server", "variable", flagEnv("serve"))
+	}
+
+	if port := os.Getenv("PORT"); port != "" && set["serve"] {
+		if f := fs.Lookup("serve"); f != nil && f.Value.String() != "" {
+			host, _, err := net.SplitHostPort(f.Value.String())
+			if err != nil {
+				return fmt.Errorf("serve: %w", err)
+			}
+			if err := fs.Set("serve", net.JoinHostPort(host, port)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
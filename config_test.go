@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_readConfig(t *testing.T) {
+	data := `# expmod server
+cache-size=1000
+log-level = "debug"
+`
+	cfg, err := readConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("readConfig: %v", err)
+	}
+
+	expected := map[string]string{"cache-size": "1000", "log-level": "debug"}
+	if len(cfg) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, cfg)
+	}
+	for k, v := range expected {
+		if cfg[k] != v {
+			t.Fatalf("%s: expected %q, got %q", k, v, cfg[k])
+		}
+	}
+}
+
+func Test_readConfigError(t *testing.T) {
+	for _, data := range []string{"cache-size 1000", "format = json", "serve = :8080"} {
+		if _, err := readConfig(strings.NewReader(data)); err == nil {
+			t.Fatalf("%q: expected error", data)
+		}
+	}
+}
+
+func Test_flagEnv(t *testing.T) {
+	if env := flagEnv("cache-size"); env != "EXPMOD_CACHE_SIZE" {
+		t.Fatalf("expected EXPMOD_CACHE_SIZE, got %q", env)
+	}
+}
+
+func Test_applyConfig(t *testing.T) {
+	var (
+		addr  string
+		size  = 512
+		ttl   string
+		level = slog.LevelInfo
+	)
+	fs := flag.NewFlagSet("expmod", flag.ContinueOnError)
+	fs.StringVar(&addr, "serve", "", "")
+	fs.IntVar(&size, "cache-size", size, "")
+	fs.StringVar(&ttl, "cache-ttl", "", "")
+	fs.TextVar(&level, "log-level", level, "")
+
+	file := filepath.Join(t.TempDir(), "expmod.conf")
+	data := "cache-size = 100\ncache-ttl = 1h\nlog-level = warn\n"
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// flags > env > config file
+	t.Setenv("EXPMOD_CACHE_TTL", "2h")
+	t.Setenv("EXPMOD_LOG_LEVEL", "error")
+	t.Setenv("PORT", "9090")
+	if err := fs.Parse([]string{"-serve", "localhost:8080", "-log-level", "debug"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if err := applyConfig(fs, file); err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+
+	if addr != "localhost:9090" {
+		t.Fatalf("serve: expected PORT to override port, got %q", addr)
+	}
+	if size != 100 {
+		t.Fatalf("cache-size: expected config value 100, got %d", size)
+	}
+	if ttl != "2h" {
+		t.Fatalf("cache-ttl: expected env value 2h, got %q", ttl)
+	}
+	if level != slog.LevelDebug {
+		t.Fatalf("log-level: expected flag value debug, got %v", level)
+	}
+}
+
+func Test_applyConfigServe(t *testing.T) {
+	var addr string
+	fs := flag.NewFlagSet("expmod", flag.ContinueOnError)
+	fs.StringVar(&addr, "serve", "", "")
+
+	t.Setenv("EXPMOD_SERVE", ":8080")
+	t.Setenv("PORT", "9090")
+	if err := applyConfig(fs, ""); err != nil {
+		t.Fatalf("applyConfig: %v", err)
+	}
+	if addr != "" {
+		t.Fatalf("expected no server without -serve, got %q", addr)
+	}
+}
+
+func Test_applyConfigBadValue(t *testing.T) {
+	size := 512
+	fs := flag.NewFlagSet("expmod", flag.ContinueOnError)
+	fs.IntVar(&size, "cache-size", size, "")
+
+	t.Setenv("EXPMOD_CACHE_SIZE", "many")
+	err := applyConfig(fs, "")
+	if err == nil || !strings.Contains(err.Error(), "EXPMOD_CACHE_SIZE") {
+		t.Fatalf("expected error naming the variable, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"regexp"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Deprecation is a module deprecation from its latest go.mod.
+type Deprecation struct {
+	Message     string
+	Replacement string `json:",omitempty"` // suggested module, if found in message
+}
+
+// Retraction is a retraction of the pinned version.
+type Retraction struct {
+	Rationale string `json:",omitempty"`
+	Latest    string // latest version, which has the retract directive
+}
+
+var checkDeprecated bool
+
+// useRE finds module paths in messages such as "use example.com/new instead".
+var useRE = regexp.MustCompile(`(?i)\b(?:use|see|moved to|replaced by)\s+` + "`?" + `([a-z0-9.-]+\.[a-z]{2,}(?:/[a-zA-Z0-9._~-]+)+)`)
+
+// deprecationReplacement returns the module suggested in a deprecation
+// message, or "".
+func deprecationReplacement(msg string) string {
+	m := useRE.FindStringSubmatch(msg)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// isRetracted returns the retraction of version in f, or nil.
+func isRetracted(f *modfile.File, version string) *modfile.Retract {
+	for _, r := range f.Retract {
+		if semver.Compare(version, r.Low) >= 0 && semver.Compare(version, r.High) <= 0 {
+			return r
+		}
+	}
+	return nil
+}
+
+// moduleStatus returns the deprecation of the module and the retraction of
+// its version, both are nil if not found.
+// The status comes from the go.mod of the latest version.
+func moduleStatus(ctx context.Context, mod module.Version, cache repoCache) (*Deprecation, *Retraction, error) {
+	lctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	latest, err := proxyLatest(lctx, mod.Path)
+	cancel()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := modFile(ctx, module.Version{Path: mod.Path, Version: latest}, cache)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dep *Deprecation
+	if f.Module != nil && f.Module.Deprecated != "" {
+		msg := f.Module.Deprecated
+		dep = &Deprecation{Message: msg, Replacement: deprecationReplacement(msg)}
+	}
+
+	var ret *Retraction
+	if r := isRetracted(f, mod.Version); r != nil {
+		ret = &Retraction{Rationale: r.Rationale, Latest: latest}
+	}
+
+	return dep, ret, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+func Test_deprecationReplacement(t *testing.T) {
+	cases := []struct {
+		msg  string
+		want string
+	}{
+		{"use github.com/new/mod instead", "github.com/new/mod"},
+		{"This module is unmaintained. Use `example.com/x/v2`.", "example.com/x/v2"},
+		{"moved to gopkg.in/yaml.v3", "gopkg.in/yaml.v3"},
+		{"no longer maintained", ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.msg, func(t *testing.T) {
+			got := deprecationReplacement(tc.msg)
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_moduleStatus(t *testing.T) {
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@latest":       `{"Version": "v1.4.0"}`,
+		"/github.com/apple/a/@v/v1.4.0.mod": "// Deprecated: use github.com/apple/a2 instead.\nmodule github.com/apple/a\n\nretract [v1.2.0, v1.2.9] // data corruption\n",
+	})
+	cache := mapCache{m: make(map[string]string)}
+
+	dep, ret, err := moduleStatus(t.Context(), module.Version{Path: "github.com/apple/a", Version: "v1.2.3"}, cache)
+	if err != nil {
+		t.Fatalf("moduleStatus: %v", err)
+	}
+	if dep == nil || dep.Replacement != "github.com/apple/a2" {
+		t.Fatalf("bad deprecation: %+v", dep)
+	}
+	if ret == nil || ret.Rationale != "data corruption" || ret.Latest != "v1.4.0" {
+		t.Fatalf("bad retraction: %+v", ret)
+	}
+
+	_, ret, err = moduleStatus(t.Context(), module.Version{Path: "github.com/apple/a", Version: "v1.3.0"}, cache)
+	if err != nil {
+		t.Fatalf("moduleStatus: %v", err)
+	}
+	if ret != nil {
+		t.Fatalf("v1.3.0: unexpected retraction: %+v", ret)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls with the same key, only the first
+// caller runs the lookup and the others wait for its result.
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+type flightCall[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+	dups int
+}
+
+// Upstream lookups shared by all requests.
+var (
+	repoFlights flightGroup[string]     // module path -> repository
+	descFlights flightGroup[string]     // owner/repo -> description
+	metaFlights flightGroup[githubRepo] // owner/repo -> repository
+	modFlights  flightGroup[[]byte]     // module@version -> go.mod
+)
+
+// Do runs fn once for concurrent calls with the same key and returns its
+// result, shared is true if the result was shared with other callers.
+// fn runs with ctx values but without its cancellation, so a caller giving up
+// doesn't fail the others, every caller still returns when its ctx is done.
+func (g *flightGroup[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (val T, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.dups++
+	} else {
+		c = &flightCall[T]{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(context.WithoutCancel(ctx), key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = c.dups > 0
+		g.mu.Unlock()
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ok, ctx.Err()
+	}
+}
+
+func (g *flightGroup[T]) run(ctx context.Context, key string, c *flightCall[T], fn func(ctx context.Context) (T, error)) {
+	c.val, c.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitDups waits until n callers are waiting for key.
+func waitDups[T any](t *testing.T, g *flightGroup[T], key string, n int) {
+	t.Helper()
+
+	for range 100 {
+		g.mu.Lock()
+		c := g.calls[key]
+		dups := 0
+		if c != nil {
+			dups = c.dups
+		}
+		g.mu.Unlock()
+		if dups == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%q: timeout waiting for %d callers", key, n)
+}
+
+func TestFlightGroup(t *testing.T) {
+	var (
+		g       flightGroup[string]
+		calls   atomic.Int32
+		release = make(chan struct{})
+	)
+	fn := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "v", nil
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	results := make([]string, n)
+	shared := make([]bool, n)
+	for i := range n {
+		wg.Go(func() {
+			var err error
+			results[i], shared[i], err = g.Do(t.Context(), "k", fn)
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+		})
+	}
+	waitDups(t, &g, "k", n-1)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+	for i := range n {
+		if results[i] != "v" || !shared[i] {
+			t.Fatalf("%d: expected shared v, got %q (%v)", i, results[i], shared[i])
+		}
+	}
+
+	// Done calls are forgotten.
+	if _, shared, _ := g.Do(t.Context(), "k", func(context.Context) (string, error) { return "w", nil }); shared {
+		t.Fatal("expected new call not to be shared")
+	}
+}
+
+func TestFlightGroupCancel(t *testing.T) {
+	var g flightGroup[string]
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		<-release
+		return "v", ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	errc := make(chan error)
+	go func() {
+		_, _, err := g.Do(ctx, "k", fn)
+		errc <- err
+	}()
+
+	valc := make(chan string)
+	go func() {
+		v, _, _ := g.Do(t.Context(), "k", fn)
+		valc <- v
+	}()
+	waitDups(t, &g, "k", 1)
+
+	// The first caller gives up, the lookup goes on for the second.
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	close(release)
+	if v := <-valc; v != "v" {
+		t.Fatalf("expected v, got %q", v)
+	}
+}
+
+func Test_depInfoCoalesced(t *testing.T) {
+	var hits atomic.Int32
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/pkg/errors" {
+			http.NotFound(w, r)
+			return
+		}
+		hits.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		_, _ = io.WriteString(w, `{"description":"Simple error handling primitives"}`)
+	})
+	defer restore()
+
+	cache, err := newServerCache(8, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			info, ok := depInfo(t.Context(), "github.com/pkg/errors", "v0.9.1", cache)
+			if !ok || info.Desc != "Simple error handling primitives" {
+				t.Errorf("unexpected %+v (%v)", info, ok)
+			}
+		})
+	}
+	wg.Wait()
+
+	if hits.Load() != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", hits.Load())
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Fork is the upstream information of a dependency repository which is a
+// GitHub fork.
+type Fork struct {
+	Upstream string // e.g. github.com/sirupsen/logrus
+	Ahead    int    // commits in the fork not in upstream
+	Behind   int    // commits in upstream not in the fork
+
+	Pushed         time.Time // last push to the fork
+	UpstreamPushed time.Time
+	// UpstreamMoreActive is set if upstream was pushed to after the fork.
+	UpstreamMoreActive bool
+}
+
+var checkForks bool
+
+// CanMoveBack reports whether the fork has no commits upstream lacks.
+func (f Fork) CanMoveBack() bool {
+	return f.Ahead == 0
+}
+
+func (f Fork) String() string {
+	s := fmt.Sprintf("fork of %s: %d ahead, %d behind", f.Upstream, f.Ahead, f.Behind)
+	if f.UpstreamMoreActive {
+		s += ", upstream more active"
+	}
+	if f.CanMoveBack() {
+		s += ", can move back to upstream"
+	}
+	return s
+}
+
+// repoFork returns the upstream information of the repository, nil if it's
+// not a fork.
+func repoFork(ctx context.Context, owner, repo string, cache repoCache) (*Fork, error) {
+	r, err := repoMeta(ctx, owner, repo, cache)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Fork || r.Parent == nil {
+		return nil, nil
+	}
+
+	// Compare fork default branch (head) to upstream default branch (base).
+	var cmp struct {
+		AheadBy  int `json:"ahead_by"`
+		BehindBy int `json:"behind_by"`
+	}
+	head := fmt.Sprintf("%s:%s", r.Owner.Login, r.DefaultBranch)
+	path := fmt.Sprintf("/repos/%s/compare/%s...%s", r.Parent.FullName, url.PathEscape(r.Parent.DefaultBranch), url.PathEscape(head))
+	if err := githubGet(ctx, path, &cmp); err != nil {
+		return nil, err
+	}
+
+	return &Fork{
+		Upstream:           "github.com/" + r.Parent.FullName,
+		Ahead:              cmp.AheadBy,
+		Behind:             cmp.BehindBy,
+		Pushed:             r.PushedAt,
+		UpstreamPushed:     r.Parent.PushedAt,
+		UpstreamMoreActive: r.Parent.PushedAt.After(r.PushedAt),
+	}, nil
+}
+
+// forkEntry is a cached fork, Fork is nil for repositories which are not forks.
+type forkEntry struct {
+	Fork    *Fork
+	Fetched time.Time
+}
+
+// depFork returns the fork information of the repository, using the cache.
+// Cached information older than healthTTL is fetched again.
+func depFork(ctx context.Context, owner, repo string, cache repoCache) (*Fork, error) {
+	key := fmt.Sprintf("fork:%s/%s", owner, repo)
+	if data, ok := cache.Get(key); ok {
+		var e forkEntry
+		if err := json.Unmarshal([]byte(data), &e); err == nil && time.Since(e.Fetched) < healthTTL {
+			return e.Fork, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	f, err := repoFork(ctx, owner, repo, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(forkEntry{Fork: f, Fetched: time.Now()})
+	if err == nil {
+		cache.Set(key, string(data))
+	}
+	return f, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_depFork(t *testing.T) {
+	calls := 0
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/repos/banana/logrus":
+			_, _ = w.Write([]byte(`{
+				"full_name": "banana/logrus", "fork": true, "default_branch": "main",
+				"pushed_at": "2025-01-01T00:00:00Z", "owner": {"login": "banana"},
+				"parent": {"full_name": "sirupsen/logrus", "default_branch": "master", "pushed_at": "2026-05-01T00:00:00Z"}
+			}`))
+		case "/repos/sirupsen/logrus/compare/master...banana:main":
+			_, _ = w.Write([]byte(`{"ahead_by": 0, "behind_by": 42}`))
+		case "/repos/apple/a":
+			_, _ = w.Write([]byte(`{"full_name": "apple/a", "fork": false}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer restore()
+
+	cache := mapCache{m: make(map[string]string)}
+	f, err := depFork(t.Context(), "banana", "logrus", cache)
+	if err != nil {
+		t.Fatalf("depFork: %v", err)
+	}
+	if f == nil {
+		t.Fatalf("expected fork")
+	}
+	if f.Upstream != "github.com/sirupsen/logrus" || f.Ahead != 0 || f.Behind != 42 || !f.UpstreamMoreActive || !f.CanMoveBack() {
+		t.Fatalf("bad fork: %+v", f)
+	}
+
+	f, err = depFork(t.Context(), "apple", "a", cache)
+	if err != nil || f != nil {
+		t.Fatalf("not a fork: got %+v, %v", f, err)
+	}
+
+	n := calls
+	if _, err := depFork(t.Context(), "apple", "a", cache); err != nil {
+		t.Fatalf("depFork (cached): %v", err)
+	}
+	if calls != n {
+		t.Fatalf("expected cached result, got %d calls", calls-n)
+	}
+}
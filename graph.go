@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Graph formats.
+const (
+	graphDOT     = "dot"
+	graphMermaid = "mermaid"
+)
+
+// maxGraphNodes limits the number of modules fetched from the proxy.
+const maxGraphNodes = 200
+
+var graphFormat string
+
+// depGraph is a module requirement graph, one node per module path.
+type depGraph struct {
+	Main  string
+	Nodes map[string]PkgInfo
+	Edges map[string][]string
+	// Truncated is set when not all go.mod files were fetched, edges are
+	// missing.
+	Truncated bool
+}
+
+func newDepGraph(main string) *depGraph {
+	return &depGraph{
+		Main:  main,
+		Nodes: map[string]PkgInfo{main: {Name: main}},
+		Edges: make(map[string][]string),
+	}
+}
+
+func (g *depGraph) addEdge(from, to string) {
+	for _, p := range g.Edges[from] {
+		if p == to {
+			return
+		}
+	}
+	g.Edges[from] = append(g.Edges[from], to)
+}
+
+// paths returns the node paths with the main module first.
+func (g *depGraph) paths() []string {
+	paths := make([]string, 0, len(g.Nodes))
+	for p := range g.Nodes {
+		if p != g.Main {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return append([]string{g.Main}, paths...)
+}
+
+// modFile returns the go.mod of mod, using the cache.
+func modFile(ctx context.Context, mod module.Version, cache repoCache) (*modfile.File, error) {
+	data, err := modData(ctx, mod, cache)
+	if err != nil {
+		return nil, err
+	}
+	return modfile.ParseLax(mod.String(), data, nil)
+}
+
+// modData returns the go.mod content of the module version, using the cache.
+func modData(ctx context.Context, mod module.Version, cache repoCache) ([]byte, error) {
+	key := "mod:" + mod.String()
+	if data, ok := cache.Get(key); ok {
+		return []byte(data), nil
+	}
+
+	data, _, err := modFlights.Do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+		defer cancel()
+		return proxyModFile(ctx, mod)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(key, string(data))
+	return data, nil
+}
+
+// buildGraph builds the requirement graph from "go mod graph" output or
+// from a go.mod file, fetching up to maxFetch dependencies go.mod files from
+// the module proxy.
+func buildGraph(ctx context.Context, data []byte, maxFetch int, cache repoCache) (*depGraph, error) {
+	format := inputFormat
+	if format == inputAuto {
+		format = detectInputFormat(data)
+	}
+
+	switch format {
+	case inputGraph:
+		return graphFromModGraph(bytes.NewReader(data))
+	case inputGoMod:
+		return graphFromProxy(ctx, data, maxFetch, cache)
+	}
+	return nil, fmt.Errorf("graph needs go.mod or go mod graph input")
+}
+
+// describeGraph sets the description of the nodes in g.
+func describeGraph(ctx context.Context, g *depGraph, cache repoCache) {
+	for path, node := range g.Nodes {
+		if path == g.Main {
+			continue
+		}
+		if info, ok := depInfo(ctx, path, node.Version, cache); ok {
+			info.Name = path
+			g.Nodes[path] = info
+		}
+	}
+}
+
+// graphFromModGraph keeps only the edges from the selected version of each
+// module.
+func graphFromModGraph(r io.Reader) (*depGraph, error) {
+	mg, err := parseModGraph(r)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newDepGraph(mg.Main.Path)
+	selected := []module.Version{mg.Main}
+	for _, mod := range mg.BuildList() {
+		g.Nodes[mod.Path] = PkgInfo{Name: mod.Path, Version: mod.Version}
+		selected = append(selected, mod)
+	}
+
+	for _, mod := range selected {
+		for _, dep := range mg.Edges[mod.String()] {
+			g.addEdge(mod.Path, dep.Path)
+		}
+	}
+	return g, nil
+}
+
+// graphFromProxy walks the go.mod files of the dependencies, starting with
+// the direct requirements in data. When a requirement raises the version of
+// a module that was already fetched, the go.mod of the new version is
+// fetched and replaces its edges. The graph is truncated after maxFetch
+// go.mod files.
+func graphFromProxy(ctx context.Context, data []byte, maxFetch int, cache repoCache) (*depGraph, error) {
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	main := "main"
+	if f.Module != nil {
+		main = f.Module.Mod.Path
+	}
+	g := newDepGraph(main)
+
+	var queue []module.Version
+	visit := func(from string, mod module.Version) {
+		// Modules of the same repository may require the main module back.
+		if mod.Path == main {
+			return
+		}
+		g.addEdge(from, mod.Path)
+		node, ok := g.Nodes[mod.Path]
+		if !ok || semver.Compare(mod.Version, node.Version) > 0 {
+			g.Nodes[mod.Path] = PkgInfo{Name: mod.Path, Version: mod.Version}
+			queue = append(queue, mod)
+		}
+	}
+
+	for _, require := range f.Require {
+		if !require.Indirect {
+			visit(main, require.Mod)
+		}
+	}
+
+	for fetched := 0; len(queue) > 0; {
+		mod := queue[0]
+		queue = queue[1:]
+		if g.Nodes[mod.Path].Version != mod.Version {
+			continue // raised since queued
+		}
+		if fetched == maxFetch {
+			g.Truncated = true
+			break
+		}
+		fetched++
+
+		mf, err := modFile(ctx, mod, cache)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			slog.Warn("can't get go.mod", "module", mod, "error", err)
+			g.Truncated = true
+			continue
+		}
+
+		delete(g.Edges, mod.Path) // edges of an older version
+		for _, require := range mf.Require {
+			visit(mod.Path, require.Mod)
+		}
+	}
+
+	g.prune()
+	if g.Truncated {
+		slog.Warn("graph truncated, weights are lower bounds", "max", maxFetch)
+	}
+	return g, nil
+}
+
+// prune removes the nodes which are not reachable from the main module, such
+// as requirements of versions that were raised.
+func (g *depGraph) prune() {
+	keep := g.reachable(g.Main)
+	keep[g.Main] = true
+	for p := range g.Nodes {
+		if !keep[p] {
+			delete(g.Nodes, p)
+			delete(g.Edges, p)
+		}
+	}
+}
+
+// writeGraph writes g in format.
+func writeGraph(w io.Writer, g *depGraph, format string) error {
+	switch format {
+	case graphDOT:
+		return writeDOT(w, g)
+	case graphMermaid:
+		return writeMermaid(w, g)
+	}
+	return fmt.Errorf("unknown graph format %q (valid: %s, %s)", format, graphDOT, graphMermaid)
+}
+
+func nodeLabel(n PkgInfo, sep string, escape func(string) string) string {
+	parts := []string{escape(n.Name)}
+	if n.Version != "" {
+		parts = append(parts, escape(n.Version))
+	}
+	if n.Desc != "" {
+		parts = append(parts, escape(n.Desc))
+	}
+	return strings.Join(parts, sep)
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ")
+
+func writeDOT(w io.Writer, g *depGraph) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "digraph deps {")
+	fmt.Fprintln(&buf, "\trankdir=LR;")
+	fmt.Fprintln(&buf, "\tnode [shape=box];")
+	paths := g.paths()
+	for _, p := range paths {
+		label := nodeLabel(g.Nodes[p], `\n`, dotEscaper.Replace)
+		fmt.Fprintf(&buf, "\t\"%s\" [label=\"%s\"];\n", dotEscaper.Replace(p), label)
+	}
+	for _, from := range paths {
+		for _, to := range g.Edges[from] {
+			fmt.Fprintf(&buf, "\t\"%s\" -> \"%s\";\n", dotEscaper.Replace(from), dotEscaper.Replace(to))
+		}
+	}
+	fmt.Fprintln(&buf, "}")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", " ")
+
+func writeMermaid(w io.Writer, g *depGraph) error {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "flowchart LR")
+	paths := g.paths()
+	ids := make(map[string]string, len(paths))
+	for i, p := range paths {
+		ids[p] = fmt.Sprintf("n%d", i)
+		label := nodeLabel(g.Nodes[p], "<br/>", mermaidEscaper.Replace)
+		fmt.Fprintf(&buf, "\t%s[\"%s\"]\n", ids[p], label)
+	}
+	for _, from := range paths {
+		for _, to := range g.Edges[from] {
+			fmt.Fprintf(&buf, "\t%s --> %s\n", ids[from], ids[to])
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
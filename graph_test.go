@@ -0,0 +1,217 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_graphFromModGraph(t *testing.T) {
+	file, err := os.Open("testdata/graph.txt")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close()
+
+	g, err := graphFromModGraph(file)
+	if err != nil {
+		t.Fatalf("graphFromModGraph: %v", err)
+	}
+
+	if g.Main != "example.com/test" {
+		t.Fatalf("main: got %q", g.Main)
+	}
+	if len(g.Nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %v", g.Nodes)
+	}
+	if v := g.Nodes["github.com/date/d"].Version; v != "v0.3.0" {
+		t.Fatalf("date: expected selected version v0.3.0, got %q", v)
+	}
+	// apple@v1.2.3 -> date@v0.2.0 is kept since apple@v1.2.3 is selected
+	if edges := g.Edges["github.com/apple/a"]; len(edges) != 1 || edges[0] != "github.com/date/d" {
+		t.Fatalf("apple: unexpected edges %v", edges)
+	}
+}
+
+func setupProxy(t *testing.T, mods map[string]string) {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := mods[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+
+	oldClient, oldBase := httpClient, goProxyBase
+	httpClient, goProxyBase = ts.Client(), ts.URL
+	t.Cleanup(func() {
+		httpClient, goProxyBase = oldClient, oldBase
+		ts.Close()
+	})
+}
+
+func Test_graphFromProxy(t *testing.T) {
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod":  "module github.com/apple/a\n\nrequire github.com/date/d v0.2.0\n",
+		"/github.com/banana/b/@v/v1.0.0.mod": "module github.com/banana/b\n\nrequire github.com/date/d v0.3.0\n",
+		"/github.com/date/d/@v/v0.2.0.mod":   "module github.com/date/d\n",
+	})
+
+	cache := mapCache{m: make(map[string]string)}
+	g, err := graphFromProxy(t.Context(), []byte(testGoMod), maxGraphNodes, cache)
+	if err != nil {
+		t.Fatalf("graphFromProxy: %v", err)
+	}
+
+	if len(g.Nodes) != 4 {
+		t.Fatalf("expected 4 nodes, got %v", g.Nodes)
+	}
+	if v := g.Nodes["github.com/date/d"].Version; v != "v0.3.0" {
+		t.Fatalf("date: expected v0.3.0, got %q", v)
+	}
+	if len(g.Edges["example.com/test"]) != 2 {
+		t.Fatalf("main: unexpected edges %v", g.Edges["example.com/test"])
+	}
+	if _, ok := cache.Get("mod:github.com/apple/a@v1.2.3"); !ok {
+		t.Fatalf("go.mod not cached")
+	}
+}
+
+func testGraph() *depGraph {
+	g := newDepGraph("example.com/test")
+	g.Nodes["github.com/apple/a"] = PkgInfo{Name: "github.com/apple/a", Version: "v1.2.3", Desc: `say "hi" <b>`}
+	g.addEdge("example.com/test", "github.com/apple/a")
+	g.addEdge("example.com/test", "github.com/apple/a")
+	return g
+}
+
+func Test_writeDOT(t *testing.T) {
+	var buf strings.Builder
+	if err := writeGraph(&buf, testGraph(), graphDOT); err != nil {
+		t.Fatalf("writeGraph: %v", err)
+	}
+
+	out := buf.String()
+	for _, frag := range []string{
+		"digraph deps {",
+		`"github.com/apple/a" [label="github.com/apple/a\nv1.2.3\nsay \"hi\" <b>"];`,
+		`"example.com/test" -> "github.com/apple/a";`,
+	} {
+		if !strings.Contains(out, frag) {
+			t.Fatalf("expected %q in:\n%s", frag, out)
+		}
+	}
+	if strings.Count(out, "->") != 1 {
+		t.Fatalf("duplicate edges:\n%s", out)
+	}
+}
+
+func Test_writeMermaid(t *testing.T) {
+	var buf strings.Builder
+	if err := writeGraph(&buf, testGraph(), graphMermaid); err != nil {
+		t.Fatalf("writeGraph: %v", err)
+	}
+
+	expected := `flowchart LR
+	n0["example.com/test"]
+	n1["github.com/apple/a<br/>v1.2.3<br/>say #quot;hi#quot; #lt;b#gt;"]
+	n0 --> n1
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func Test_writeGraphBadFormat(t *testing.T) {
+	if err := writeGraph(&strings.Builder{}, testGraph(), "png"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func Test_graphFromProxyRaised(t *testing.T) {
+	// cherry raises date to v0.3.0 after date v0.2.0 was fetched, the
+	// go.mod of v0.3.0 is fetched and replaces the v0.2.0 edges.
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod":  "module github.com/apple/a\n\nrequire github.com/cherry/c v1.0.0\n",
+		"/github.com/cherry/c/@v/v1.0.0.mod": "module github.com/cherry/c\n\nrequire github.com/date/d v0.3.0\n",
+		"/github.com/date/d/@v/v0.2.0.mod":   "module github.com/date/d\n\nrequire github.com/egg/e v1.0.0\n",
+		"/github.com/date/d/@v/v0.3.0.mod":   "module github.com/date/d\n\nrequire github.com/fig/f v1.0.0\n",
+		"/github.com/egg/e/@v/v1.0.0.mod":    "module github.com/egg/e\n",
+		"/github.com/fig/f/@v/v1.0.0.mod":    "module github.com/fig/f\n",
+	})
+
+	gomod := "module example.com/test\n\nrequire (\n\tgithub.com/apple/a v1.2.3\n\tgithub.com/date/d v0.2.0\n)\n"
+	cache := mapCache{m: make(map[string]string)}
+	g, err := graphFromProxy(t.Context(), []byte(gomod), maxGraphNodes, cache)
+	if err != nil {
+		t.Fatalf("graphFromProxy: %v", err)
+	}
+
+	if g.Truncated {
+		t.Fatal("unexpected truncated")
+	}
+	if _, ok := cache.Get("mod:github.com/date/d@v0.2.0"); !ok {
+		t.Fatal("date v0.2.0 not fetched")
+	}
+	if v := g.Nodes["github.com/date/d"].Version; v != "v0.3.0" {
+		t.Fatalf("date: expected v0.3.0, got %q", v)
+	}
+	if edges := g.Edges["github.com/date/d"]; len(edges) != 1 || edges[0] != "github.com/fig/f" {
+		t.Fatalf("date: expected v0.3.0 edges, got %v", edges)
+	}
+	if _, ok := g.Nodes["github.com/egg/e"]; ok {
+		t.Fatal("egg: expected orphan of date v0.2.0 to be removed")
+	}
+}
+
+func Test_graphFromProxyCycle(t *testing.T) {
+	// banana requires the main module back, as modules of the same
+	// repository often do.
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod":  "module github.com/apple/a\n\nrequire github.com/date/d v0.2.0\n",
+		"/github.com/banana/b/@v/v1.0.0.mod": "module github.com/banana/b\n\nrequire example.com/test v0.1.0\n",
+		"/github.com/date/d/@v/v0.2.0.mod":   "module github.com/date/d\n",
+		"/example.com/test/@v/v0.1.0.mod":    "module example.com/test\n\nrequire github.com/banana/b v1.0.0\n",
+	})
+
+	gomod := "module example.com/test\n\nrequire (\n\tgithub.com/apple/a v1.2.3\n\tgithub.com/banana/b v1.0.0\n)\n"
+	g, err := graphFromProxy(t.Context(), []byte(gomod), maxGraphNodes, mapCache{m: make(map[string]string)})
+	if err != nil {
+		t.Fatalf("graphFromProxy: %v", err)
+	}
+
+	if n := len(g.Edges[g.Main]); n != 2 {
+		t.Fatalf("main: expected 2 direct edges, got %v", g.Edges[g.Main])
+	}
+	weights := depWeights(g)
+	if w := weights["github.com/apple/a"]; w.Total != 1 {
+		t.Fatalf("apple: expected weight 1, got %+v", w)
+	}
+	if w := weights["github.com/banana/b"]; w.Total != 0 {
+		t.Fatalf("banana: expected weight 0, got %+v", w)
+	}
+}
+
+func Test_graphFromProxyTruncated(t *testing.T) {
+	// date's go.mod is missing
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod":  "module github.com/apple/a\n\nrequire github.com/date/d v0.2.0\n",
+		"/github.com/banana/b/@v/v1.0.0.mod": "module github.com/banana/b\n",
+	})
+
+	g, err := graphFromProxy(t.Context(), []byte(testGoMod), maxGraphNodes, mapCache{m: make(map[string]string)})
+	if err != nil {
+		t.Fatalf("graphFromProxy: %v", err)
+	}
+	if !g.Truncated {
+		t.Fatal("expected truncated")
+	}
+	if w := depWeights(g)["github.com/apple/a"]; !w.Approximate {
+		t.Fatalf("apple: expected approximate weight, got %+v", w)
+	}
+}
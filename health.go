@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health is the maintenance activity of a dependency GitHub repository.
+type Health struct {
+	Contributors int
+	Commits      int // in the last year
+	OpenIssues   int
+	OpenPRs      int
+	LastRelease  time.Time `json:",omitzero"`
+	Archived     bool      `json:",omitempty"`
+
+	Score     int // 0-100
+	Breakdown []HealthFactor
+
+	Fetched time.Time
+}
+
+// HealthFactor is a part of the health score.
+type HealthFactor struct {
+	Name   string
+	Points int
+	Max    int
+}
+
+// minHealthScore is the score below which health is highlighted.
+const minHealthScore = 50
+
+var (
+	checkHealth bool
+	// healthTTL is the cache TTL of health, which changes more often than
+	// descriptions.
+	healthTTL = 24 * time.Hour
+)
+
+var lastPageRE = regexp.MustCompile(`[?&]page=(\d+)>; rel="last"`)
+
+// githubCount returns the number of items in a GitHub list API path, using
+// the pagination of a single item per page.
+func githubCount(ctx context.Context, path string) (int, error) {
+	url := githubAPIBase + path
+	if strings.Contains(path, "?") {
+		url += "&per_page=1"
+	} else {
+		url += "?per_page=1"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if token := os.Getenv(tokenKey); token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent, http.StatusConflict: // empty repository
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("%q: %s", url, resp.Status)
+	}
+
+	if m := lastPageRE.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		return strconv.Atoi(m[1])
+	}
+
+	var items []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return 0, fmt.Errorf("%q: can't decode JSON - %w", url, err)
+	}
+	return len(items), nil
+}
+
+// repoHealth fetches the health of the GitHub repository.
+func repoHealth(ctx context.Context, owner, repo string, now time.Time, cache repoCache) (Health, error) {
+	info, err := repoMeta(ctx, owner, repo, cache)
+	if err != nil {
+		return Health{}, err
+	}
+	repoPath := fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo))
+
+	h := Health{Archived: info.Archived, Fetched: now}
+	if h.Contributors, err = githubCount(ctx, repoPath+"/contributors?anon=1"); err != nil {
+		return Health{}, err
+	}
+
+	since := now.AddDate(-1, 0, 0).UTC().Format(time.RFC3339)
+	if h.Commits, err = githubCount(ctx, repoPath+"/commits?since="+url.QueryEscape(since)); err != nil {
+		return Health{}, err
+	}
+
+	if h.OpenPRs, err = githubCount(ctx, repoPath+"/pulls?state=open"); err != nil {
+		return Health{}, err
+	}
+	h.OpenIssues = max(info.OpenIssues-h.OpenPRs, 0)
+
+	var release struct {
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := githubGet(ctx, repoPath+"/releases/latest", &release); err == nil {
+		h.LastRelease = release.PublishedAt
+	}
+
+	h.score(now)
+	return h, nil
+}
+
+// tier returns the points of the first threshold value reaches.
+func tier(value int, thresholds []int, points []int) int {
+	for i, t := range thresholds {
+		if value >= t {
+			return points[i]
+		}
+	}
+	return 0
+}
+
+// score sets the health score and its breakdown.
+// Each factor is worth 25 points, archived repositories score 0.
+func (h *Health) score(now time.Time) {
+	releaseAge := -1 // months, -1 for no release
+	if !h.LastRelease.IsZero() {
+		releaseAge = int(now.Sub(h.LastRelease).Hours() / 24 / 30)
+	}
+	releasePoints := 0
+	switch {
+	case releaseAge < 0:
+	case releaseAge < 6:
+		releasePoints = 25
+	case releaseAge < 12:
+		releasePoints = 15
+	case releaseAge < 24:
+		releasePoints = 5
+	}
+
+	h.Breakdown = []HealthFactor{
+		{"contributors", tier(h.Contributors, []int{10, 3, 2}, []int{25, 15, 8}), 25},
+		{"commits", tier(h.Commits, []int{50, 10, 1}, []int{25, 15, 5}), 25},
+		{"release", releasePoints, 25},
+		{"pull requests", 25 - tier(h.OpenPRs, []int{50, 20, 5}, []int{25, 15, 5}), 25},
+	}
+
+	h.Score = 0
+	if h.Archived {
+		return
+	}
+	for _, f := range h.Breakdown {
+		h.Score += f.Points
+	}
+}
+
+// depHealth returns the health of the GitHub repository, using the cache.
+// Cached health older than healthTTL is fetched again.
+func depHealth(ctx context.Context, owner, repo string, cache repoCache) (Health, error) {
+	key := fmt.Sprintf("health:%s/%s", owner, repo)
+	if data, ok := cache.Get(key); ok {
+		var h Health
+		if err := json.Unmarshal([]byte(data), &h); err == nil && time.Since(h.Fetched) < healthTTL {
+			return h, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	h, err := repoHealth(ctx, owner, repo, time.Now(), cache)
+	if err != nil {
+		return Health{}, err
+	}
+
+	data, err := json.Marshal(h)
+	if err == nil {
+		cache.Set(key, string(data))
+	}
+	return h, nil
+}
+
+// Low reports whether the score is below minHealthScore.
+func (h Health) Low() bool {
+	return h.Score < minHealthScore
+}
+
+// String returns the score and its breakdown.
+func (h Health) String() string {
+	parts := make([]string, len(h.Breakdown))
+	for i, f := range h.Breakdown {
+		parts[i] = fmt.Sprintf("%s %d/%d", f.Name, f.Points, f.Max)
+	}
+	s := fmt.Sprintf("%d/100 (%s)", h.Score, strings.Join(parts, ", "))
+	if h.Archived {
+		s += " archived"
+	}
+	return s
+}
+
+// Activity returns the raw activity numbers.
+func (h Health) Activity() string {
+	release := "no release"
+	if !h.LastRelease.IsZero() {
+		release = "last release " + h.LastRelease.Format(time.DateOnly)
+	}
+	return fmt.Sprintf("%d contributors, %d commits last year, %d open issues, %d open PRs, %s",
+		h.Contributors, h.Commits, h.OpenIssues, h.OpenPRs, release)
+}
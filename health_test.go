@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_repoHealth(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/apple/a":
+			_, _ = w.Write([]byte(`{"open_issues_count": 12}`))
+		case "/repos/apple/a/contributors":
+			w.Header().Set("Link", `<https://api.github.com/repositories/1/contributors?anon=1&per_page=1&page=2>; rel="next", <https://api.github.com/repositories/1/contributors?anon=1&per_page=1&page=14>; rel="last"`)
+			_, _ = w.Write([]byte(`[{}]`))
+		case "/repos/apple/a/commits":
+			if r.URL.Query().Get("since") == "" {
+				t.Errorf("commits: missing since")
+			}
+			w.Header().Set("Link", `<https://api.github.com/repositories/1/commits?per_page=1&page=30>; rel="last"`)
+			_, _ = w.Write([]byte(`[{}]`))
+		case "/repos/apple/a/pulls":
+			_, _ = w.Write([]byte(`[{}]`))
+		case "/repos/apple/a/releases/latest":
+			_, _ = w.Write([]byte(`{"published_at": "2026-01-10T00:00:00Z"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer restore()
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	h, err := repoHealth(t.Context(), "apple", "a", now, mapCache{m: make(map[string]string)})
+	if err != nil {
+		t.Fatalf("repoHealth: %v", err)
+	}
+
+	if h.Contributors != 14 || h.Commits != 30 || h.OpenPRs != 1 || h.OpenIssues != 11 {
+		t.Fatalf("bad activity: %s", h.Activity())
+	}
+	// contributors 25 + commits 15 + release (8 months) 15 + pull requests 25
+	if h.Score != 80 {
+		t.Fatalf("expected score 80, got %s", h)
+	}
+}
+
+func Test_depHealthTTL(t *testing.T) {
+	calls := 0
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/repos/apple/a" {
+			_, _ = w.Write([]byte(`{"archived": true}`))
+			return
+		}
+		if r.URL.Path == "/repos/apple/a/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	})
+	defer restore()
+
+	cache := mapCache{m: make(map[string]string)}
+	h, err := depHealth(t.Context(), "apple", "a", cache)
+	if err != nil {
+		t.Fatalf("depHealth: %v", err)
+	}
+	if h.Score != 0 || !h.Archived {
+		t.Fatalf("archived: expected score 0, got %s", h)
+	}
+
+	n := calls
+	if _, err := depHealth(t.Context(), "apple", "a", cache); err != nil {
+		t.Fatalf("depHealth (cached): %v", err)
+	}
+	if calls != n {
+		t.Fatalf("expected cached health, got %d calls", calls-n)
+	}
+
+	oldTTL := healthTTL
+	healthTTL = 0
+	defer func() { healthTTL = oldTTL }()
+	if _, err := depHealth(t.Context(), "apple", "a", cache); err != nil {
+		t.Fatalf("depHealth (expired): %v", err)
+	}
+	if calls == n {
+		t.Fatalf("expected expired health to be fetched")
+	}
+}
+
+func TestHealthRowLow(t *testing.T) {
+	for _, score := range []int{minHealthScore - 1, minHealthScore} {
+		var buf strings.Builder
+		r := row{PkgInfo: PkgInfo{Name: "a", Health: &Health{Score: score}}, HasHealth: true}
+		if err := resultsTmpl.ExecuteTemplate(&buf, "row", r); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		low := strings.Contains(buf.String(), `class="vuln"`)
+		if low != (score < minHealthScore) {
+			t.Fatalf("score %d: highlighted=%v\n%s", score, low, buf.String())
+		}
+	}
+}
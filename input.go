@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// Input formats.
+const (
+	inputAuto   = "auto"
+	inputGoMod  = "gomod"  // go.mod file
+	inputGoList = "golist" // go list -m -json all
+	inputGraph  = "graph"  // go mod graph
+)
+
+var inputFormats = []string{inputAuto, inputGoMod, inputGoList, inputGraph}
+
+var (
+	inputFormat     = inputAuto
+	includeIndirect bool
+	// readGoListMain allows reading the main module go.mod named in go list
+	// output, the web server turns it off.
+	readGoListMain = true
+)
+
+// requirement is a module in the dependency list.
+type requirement struct {
+	Mod      module.Version
+	Indirect bool
+	Replace  *module.Version
+}
+
+// detectInputFormat guesses the format of data.
+func detectInputFormat(data []byte) string {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		return inputGoList
+	}
+
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 2 && strings.Contains(fields[1], "@") {
+		return inputGraph
+	}
+
+	return inputGoMod
+}
+
+// parseRequires parses data in format and returns the dependencies to
+// describe, sorted by path. Indirect dependencies are kept only if indirect
+// is set.
+func parseRequires(data []byte, format string, indirect bool) ([]requirement, error) {
+	if format == inputAuto || format == "" {
+		format = detectInputFormat(data)
+	}
+
+	var (
+		reqs []requirement
+		err  error
+	)
+	switch format {
+	case inputGoMod:
+		reqs, err = goModRequires(data)
+	case inputGoList:
+		reqs, err = goListRequires(bytes.NewReader(data))
+	case inputGraph:
+		reqs, err = graphRequires(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown input format %q (valid: %s)", format, strings.Join(inputFormats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !indirect {
+		reqs = slices.DeleteFunc(reqs, func(r requirement) bool { return r.Indirect })
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].Mod.Path < reqs[j].Mod.Path
+	})
+	return reqs, nil
+}
+
+// goModRequires returns the requirements in a go.mod file.
+func goModRequires(data []byte) ([]requirement, error) {
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqs []requirement
+	for _, require := range f.Require {
+		reqs = append(reqs, requirement{Mod: require.Mod, Indirect: require.Indirect})
+	}
+	return reqs, nil
+}
+
+// mainModule returns the main module path in data, or "" if not found.
+func mainModule(data []byte) string {
+	format := inputFormat
+	if format == inputAuto {
+		format = detectInputFormat(data)
+	}
+
+	switch format {
+	case inputGoList:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var m listModule
+			if err := dec.Decode(&m); err != nil {
+				return ""
+			}
+			if m.Main {
+				return m.Path
+			}
+		}
+	case inputGraph:
+		g, err := parseModGraph(bytes.NewReader(data))
+		if err != nil {
+			return ""
+		}
+		return g.Main.Path
+	}
+	return modfile.ModulePath(data)
+}
+
+// listModule is a module in "go list -m -json" output.
+type listModule struct {
+	Path      string
+	Version   string
+	Main      bool
+	Indirect  bool
+	GoMod     string // go.mod file name
+	GoVersion string
+	Replace   *listModule
+}
+
+// goListRequires returns the build list from "go list -m -json all" output.
+// go list marks only "// indirect" go.mod requirements as indirect, so
+// modules missing from the main go.mod are indirect too when it's readable.
+func goListRequires(r io.Reader) ([]requirement, error) {
+	var (
+		reqs    []requirement
+		mainMod string
+	)
+	dec := json.NewDecoder(r)
+	for {
+		var m listModule
+		err := dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("go list: %w", err)
+		}
+
+		if m.Main {
+			mainMod = m.GoMod
+			continue
+		}
+
+		req := requirement{
+			Mod:      module.Version{Path: m.Path, Version: m.Version},
+			Indirect: m.Indirect,
+		}
+		if m.Replace != nil {
+			req.Replace = &module.Version{Path: m.Replace.Path, Version: m.Replace.Version}
+		}
+		reqs = append(reqs, req)
+	}
+
+	if direct, ok := goModDirect(mainMod); ok {
+		for i := range reqs {
+			if !direct[reqs[i].Mod.Path] {
+				reqs[i].Indirect = true
+			}
+		}
+	}
+	return reqs, nil
+}
+
+// goModDirect returns the direct requirements in the go.mod file fileName,
+// ok is false if it can't be read.
+func goModDirect(fileName string) (direct map[string]bool, ok bool) {
+	if !readGoListMain || fileName == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(fileName) // #nosec G304
+	if err != nil {
+		slog.Warn("can't read main go.mod, modules missing from it are shown as direct", "error", err)
+		return nil, false
+	}
+	f, err := modfile.ParseLax(fileName, data, nil)
+	if err != nil {
+		slog.Warn("can't parse main go.mod", "file", fileName, "error", err)
+		return nil, false
+	}
+
+	direct = make(map[string]bool)
+	for _, r := range f.Require {
+		if !r.Indirect {
+			direct[r.Mod.Path] = true
+		}
+	}
+	return direct, true
+}
+
+// graphRequires returns the build list from "go mod graph" output.
+// The selected version of each module is the highest one in the graph, as
+// in MVS. Modules required by the main module are direct, the rest are
+// indirect.
+func graphRequires(r io.Reader) ([]requirement, error) {
+	g, err := parseModGraph(r)
+	if err != nil {
+		return nil, err
+	}
+
+	direct := make(map[string]bool)
+	for _, dep := range g.Edges[g.Main.String()] {
+		direct[dep.Path] = true
+	}
+
+	var reqs []requirement
+	for _, mod := range g.BuildList() {
+		reqs = append(reqs, requirement{Mod: mod, Indirect: !direct[mod.Path]})
+	}
+	return reqs, nil
+}
+
+// modGraph is a module requirement graph.
+type modGraph struct {
+	Main module.Version
+	// Edges maps a module ("path@version") to its requirements.
+	Edges map[string][]module.Version
+}
+
+// BuildList returns the selected version of every module except main.
+func (g *modGraph) BuildList() []module.Version {
+	selected := make(map[string]string)
+	for _, deps := range g.Edges {
+		for _, dep := range deps {
+			if dep.Path == g.Main.Path {
+				continue
+			}
+			if v, ok := selected[dep.Path]; !ok || semver.Compare(dep.Version, v) > 0 {
+				selected[dep.Path] = dep.Version
+			}
+		}
+	}
+
+	mods := make([]module.Version, 0, len(selected))
+	for p, v := range selected {
+		mods = append(mods, module.Version{Path: p, Version: v})
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Path < mods[j].Path })
+	return mods
+}
+
+// parseModGraph parses "go mod graph" output.
+func parseModGraph(r io.Reader) (*modGraph, error) {
+	g := modGraph{Edges: make(map[string][]module.Version)}
+	s := bufio.NewScanner(r)
+	for lnum := 1; s.Scan(); lnum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("graph:%d: bad line - %q", lnum, line)
+		}
+
+		from, to := graphNode(fields[0]), graphNode(fields[1])
+		if from.Version == "" && g.Main.Path == "" {
+			g.Main = from
+		}
+		if to.Path == "go" || to.Path == "toolchain" {
+			continue
+		}
+		g.Edges[from.String()] = append(g.Edges[from.String()], to)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("graph: %w", err)
+	}
+	if g.Main.Path == "" {
+		return nil, fmt.Errorf("graph: can't find main module")
+	}
+	return &g, nil
+}
+
+func graphNode(s string) module.Version {
+	path, version, _ := strings.Cut(s, "@")
+	return module.Version{Path: path, Version: version}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var detectCases = []struct {
+	file   string
+	format string
+}{
+	{"testdata/go.mod", inputGoMod},
+	{"testdata/golist.json", inputGoList},
+	{"testdata/graph.txt", inputGraph},
+}
+
+func Test_detectInputFormat(t *testing.T) {
+	for _, tc := range detectCases {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(tc.file)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+
+			if format := detectInputFormat(data); format != tc.format {
+				t.Fatalf("expected %q, got %q", tc.format, format)
+			}
+		})
+	}
+}
+
+func Test_goListRequires(t *testing.T) {
+	data, err := os.ReadFile("testdata/golist.json")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	reqs, err := goListRequires(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("goListRequires: %v", err)
+	}
+
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 modules (no main), got %d", len(reqs))
+	}
+
+	banana := reqs[1]
+	if banana.Replace == nil || banana.Replace.String() != "github.com/cherry/b@v1.0.1" {
+		t.Fatalf("banana: bad replace %+v", banana.Replace)
+	}
+	if !reqs[2].Indirect {
+		t.Fatalf("date: expected indirect")
+	}
+}
+
+func Test_goListRequiresMainGoMod(t *testing.T) {
+	dir := t.TempDir()
+	goMod := filepath.Join(dir, "go.mod")
+	data := "module example.com/test\n\nrequire github.com/apple/a v1.2.3\n"
+	if err := os.WriteFile(goMod, []byte(data), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	out := fmt.Sprintf(`{"Path": "example.com/test", "Main": true, "GoMod": %q}
+{"Path": "github.com/apple/a", "Version": "v1.2.3"}
+{"Path": "github.com/banana/b", "Version": "v1.0.0"}
+`, goMod)
+	reqs, err := goListRequires(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("goListRequires: %v", err)
+	}
+
+	// banana isn't in go.mod, go list doesn't mark it indirect
+	if len(reqs) != 2 || reqs[0].Indirect || !reqs[1].Indirect {
+		t.Fatalf("expected only apple direct, got %+v", reqs)
+	}
+
+	readGoListMain = false
+	defer func() { readGoListMain = true }()
+	reqs, err = goListRequires(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("goListRequires: %v", err)
+	}
+	if reqs[1].Indirect {
+		t.Fatalf("expected go list flags when go.mod isn't read, got %+v", reqs)
+	}
+}
+
+var parseIndirectCases = []struct {
+	file   string
+	direct int
+	all    int
+}{
+	{"testdata/golist.json", 2, 3},
+	{"testdata/graph.txt", 2, 3},
+}
+
+func Test_parseRequiresIndirect(t *testing.T) {
+	for _, tc := range parseIndirectCases {
+		t.Run(tc.file, func(t *testing.T) {
+			data, err := os.ReadFile(tc.file)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+
+			for indirect, expected := range map[bool]int{false: tc.direct, true: tc.all} {
+				reqs, err := parseRequires(data, inputAuto, indirect)
+				if err != nil {
+					t.Fatalf("parseRequires: %v", err)
+				}
+				if len(reqs) != expected {
+					t.Fatalf("indirect=%v: expected %d, got %+v", indirect, expected, reqs)
+				}
+			}
+		})
+	}
+}
+
+func Test_graphRequires(t *testing.T) {
+	file, err := os.Open("testdata/graph.txt")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close()
+
+	reqs, err := graphRequires(file)
+	if err != nil {
+		t.Fatalf("graphRequires: %v", err)
+	}
+
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 modules, got %+v", reqs)
+	}
+
+	date := reqs[2]
+	if date.Mod.Path != "github.com/date/d" || date.Mod.Version != "v0.3.0" {
+		t.Fatalf("date: expected MVS version v0.3.0, got %v", date.Mod)
+	}
+	if !date.Indirect || reqs[0].Indirect {
+		t.Fatalf("bad indirect flags: %+v", reqs)
+	}
+}
+
+func Test_parseRequiresBadFormat(t *testing.T) {
+	if _, err := parseRequires([]byte(testGoMod), "toml", false); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func Test_pkgsInfoGoList(t *testing.T) {
+	cache := mapCache{m: map[string]string{
+		"apple/a":  "desc A",
+		"cherry/b": "desc B",
+		"date/d":   "desc D",
+	}}
+
+	file, err := os.Open("testdata/golist.json")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close()
+
+	pkgs, err := pkgsInfo(t.Context(), file, cache)
+	if err != nil {
+		t.Fatalf("pkgsInfo: %v", err)
+	}
+
+	// date/d is indirect
+	if len(pkgs) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(pkgs))
+	}
+
+	banana := pkgs[1]
+	if banana.Name != "github.com/banana/b" || banana.Desc != "desc B" || banana.Replace != "github.com/cherry/b@v1.0.1" {
+		t.Fatalf("banana: unexpected %+v", banana)
+	}
+	if banana.URL != "https://github.com/cherry/b" {
+		t.Fatalf("banana: expected replacement URL, got %q", banana.URL)
+	}
+}
+
+// cancelCache cancels the context when key is looked up.
+type cancelCache struct {
+	mapCache
+	key    string
+	cancel context.CancelFunc
+}
+
+func (c cancelCache) Get(key string) (string, bool) {
+	if key == c.key {
+		c.cancel()
+	}
+	return c.mapCache.Get(key)
+}
+
+func Test_pkgsInfoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cache := cancelCache{
+		mapCache: mapCache{m: map[string]string{
+			"apple/a":  "desc A",
+			"cherry/b": "desc B",
+			"date/d":   "desc D",
+		}},
+		key:    "cherry/b",
+		cancel: cancel,
+	}
+
+	file, err := os.Open("testdata/golist.json")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer file.Close()
+
+	pkgs, err := pkgsInfo(ctx, file, cache)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "github.com/apple/a" {
+		t.Fatalf("expected only apple, got %+v", pkgs)
+	}
+}
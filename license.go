@@ -0,0 +1,352 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+//go:embed licenses
+var licensesFS embed.FS
+
+// License is a dependency license.
+type License struct {
+	ID         string  // SPDX ID
+	Confidence float64 // 0-1, 1 for license reported by GitHub
+	Source     string  // "github", license file name
+}
+
+// minLicenseConfidence is the minimal classification score for a license.
+const minLicenseConfidence = 0.75
+
+var detectLicenses bool
+
+// licenseFiles are the names of license files, in search order.
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING", "LICENCE"}
+
+type licenseTemplate struct {
+	id       string
+	trigrams map[string]bool
+}
+
+var licenseTemplates = loadLicenseTemplates()
+
+func loadLicenseTemplates() []licenseTemplate {
+	entries, err := licensesFS.ReadDir("licenses")
+	if err != nil {
+		panic(err)
+	}
+
+	var templates []licenseTemplate
+	for _, e := range entries {
+		data, err := licensesFS.ReadFile("licenses/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		id := strings.TrimSuffix(e.Name(), ".txt")
+		templates = append(templates, licenseTemplate{id: id, trigrams: trigrams(string(data))})
+	}
+	return templates
+}
+
+var (
+	copyrightRE = regexp.MustCompile(`(?im)^\s*copyright.*$`)
+	nonWordRE   = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// trigrams returns the word trigrams of the normalized text.
+func trigrams(text string) map[string]bool {
+	text = copyrightRE.ReplaceAllString(text, " ")
+	text = nonWordRE.ReplaceAllString(strings.ToLower(text), " ")
+	words := strings.Fields(text)
+
+	grams := make(map[string]bool)
+	for i := 0; i+3 <= len(words); i++ {
+		grams[strings.Join(words[i:i+3], " ")] = true
+	}
+	return grams
+}
+
+// classifyLicense returns the SPDX ID of text and the confidence score, the
+// Dice coefficient of the text and license trigrams.
+// id is "" if no license matches with at least minLicenseConfidence.
+func classifyLicense(text string) (string, float64) {
+	grams := trigrams(text)
+	if len(grams) == 0 {
+		return "", 0
+	}
+
+	type score struct {
+		id    string
+		value float64
+	}
+	var scores []score
+	for _, t := range licenseTemplates {
+		common := 0
+		for g := range t.trigrams {
+			if grams[g] {
+				common++
+			}
+		}
+
+		value := 2 * float64(common) / float64(len(t.trigrams)+len(grams))
+		scores = append(scores, score{t.id, value})
+	}
+
+	sort.Slice(scores, func(i, j int) bool { return scores[i].value > scores[j].value })
+	best := scores[0]
+	if best.value < minLicenseConfidence {
+		return "", best.value
+	}
+	return best.id, best.value
+}
+
+// deprecatedLicenses maps deprecated SPDX IDs, which GitHub still reports, to
+// their replacements.
+var deprecatedLicenses = map[string]string{
+	"AGPL-1.0":  "AGPL-1.0-only",
+	"AGPL-3.0":  "AGPL-3.0-only",
+	"GPL-1.0":   "GPL-1.0-only",
+	"GPL-1.0+":  "GPL-1.0-or-later",
+	"GPL-2.0":   "GPL-2.0-only",
+	"GPL-2.0+":  "GPL-2.0-or-later",
+	"GPL-3.0":   "GPL-3.0-only",
+	"GPL-3.0+":  "GPL-3.0-or-later",
+	"LGPL-2.0":  "LGPL-2.0-only",
+	"LGPL-2.0+": "LGPL-2.0-or-later",
+	"LGPL-2.1":  "LGPL-2.1-only",
+	"LGPL-2.1+": "LGPL-2.1-or-later",
+	"LGPL-3.0":  "LGPL-3.0-only",
+	"LGPL-3.0+": "LGPL-3.0-or-later",
+}
+
+// canonicalLicense returns the current SPDX ID of id.
+// e.g. GPL-3.0 -> GPL-3.0-only
+func canonicalLicense(id string) string {
+	for old, current := range deprecatedLicenses {
+		if strings.EqualFold(id, old) {
+			return current
+		}
+	}
+	return id
+}
+
+// validSPDX reports whether GitHub reported a real SPDX ID.
+func validSPDX(id string) bool {
+	return id != "" && id != spdxNoAssertion && id != "NONE"
+}
+
+// githubLicense returns the SPDX ID GitHub detected for the repository.
+func githubLicense(ctx context.Context, owner, repo string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/license", githubAPIBase, url.PathEscape(owner), url.PathEscape(repo))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if token := os.Getenv(tokenKey); token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%q: %s", url, resp.Status)
+	}
+
+	var reply struct {
+		License struct {
+			SPDXID string `json:"spdx_id"`
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return "", fmt.Errorf("%q: can't decode JSON - %w", url, err)
+	}
+
+	return reply.License.SPDXID, nil
+}
+
+// gitRef returns the git ref and the module sub directory in the repository
+// of the module version.
+// e.g. github.com/a/b/sub/v2 v2.1.0 -> sub/v2.1.0, sub
+func gitRef(repoPath string, mod module.Version) (string, string) {
+	version := strings.TrimSuffix(mod.Version, "+incompatible")
+	if module.IsPseudoVersion(version) {
+		if rev, err := module.PseudoVersionRev(version); err == nil {
+			version = rev
+		}
+	}
+
+	prefix, _, _ := module.SplitPathVersion(mod.Path)
+	dir := strings.Trim(strings.TrimPrefix(prefix, repoPath), "/")
+	if dir == "" || module.IsPseudoVersion(mod.Version) {
+		return version, dir
+	}
+	return dir + "/" + version, dir
+}
+
+// rawLicense returns the license file of the module from the GitHub raw
+// host.
+func rawLicense(ctx context.Context, owner, repo string, mod module.Version) (string, string, error) {
+	ref, dir := gitRef(fmt.Sprintf("github.com/%s/%s", owner, repo), mod)
+	var dirs []string
+	if dir != "" {
+		dirs = append(dirs, dir)
+	}
+	dirs = append(dirs, "")
+
+	for _, d := range dirs {
+		for _, name := range licenseFiles {
+			rawURL := fmt.Sprintf("%s/%s/%s/%s/%s", githubRawBase,
+				url.PathEscape(owner), url.PathEscape(repo), ref, path.Join(d, name))
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+			if err != nil {
+				return "", "", err
+			}
+
+			resp, err := httpClient.Do(req) //#nosec G704
+			if err != nil {
+				return "", "", err
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close() //#nosec CWE-703
+				continue
+			}
+
+			data, err := io.ReadAll(io.LimitReader(resp.Body, maxLicenseSize))
+			resp.Body.Close() //#nosec CWE-703
+			if err != nil {
+				return "", "", err
+			}
+			return string(data), name, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s: no license file found", mod)
+}
+
+const (
+	maxLicenseSize = 1 << 20
+	maxZipSize     = 64 << 20
+)
+
+// zipLicense returns the license file from the module zip in the proxy.
+func zipLicense(ctx context.Context, mod module.Version) (string, string, error) {
+	version, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := proxyGetLimit(ctx, mod.Path, version+".zip", maxZipSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: bad zip - %w", mod, err)
+	}
+
+	prefix := mod.String() + "/"
+	for _, name := range licenseFiles {
+		for _, f := range zr.File {
+			if f.Name != prefix+name {
+				continue
+			}
+
+			r, err := f.Open()
+			if err != nil {
+				return "", "", err
+			}
+			data, err := io.ReadAll(io.LimitReader(r, maxLicenseSize))
+			r.Close() //#nosec CWE-703
+			if err != nil {
+				return "", "", err
+			}
+			return string(data), name, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s: no license file found", mod)
+}
+
+// detectLicense returns the license of the module, repoPath is the GitHub
+// repository (e.g. github.com/tebeka/expmod) or "".
+// The license file of the module version is classified, GitHub's license is
+// used only if the file is missing or unknown.
+func detectLicense(ctx context.Context, mod module.Version, repoPath string) (License, error) {
+	owner, repo := repoInfo(repoPath)
+	if owner == "" {
+		text, name, err := zipLicense(ctx, mod)
+		if err != nil {
+			return License{}, err
+		}
+		return classifyFile(mod, text, name)
+	}
+
+	text, name, err := rawLicense(ctx, owner, repo, mod)
+	if err == nil {
+		var lic License
+		if lic, err = classifyFile(mod, text, name); err == nil {
+			return lic, nil
+		}
+	}
+
+	// GitHub knows more licenses.
+	if id, gerr := githubLicense(ctx, owner, repo); gerr == nil && validSPDX(id) {
+		return License{ID: canonicalLicense(id), Confidence: 1, Source: "github"}, nil
+	}
+	return License{}, err
+}
+
+// classifyFile classifies the license file name of mod.
+func classifyFile(mod module.Version, text, name string) (License, error) {
+	id, score := classifyLicense(text)
+	if id == "" {
+		return License{}, fmt.Errorf("%s: unknown license in %s (best score %.2f)", mod, name, score)
+	}
+	return License{ID: id, Confidence: score, Source: name}, nil
+}
+
+// moduleLicense returns the license of the module, using the cache.
+func moduleLicense(ctx context.Context, mod module.Version, repoPath string, cache repoCache) (License, error) {
+	key := "license:" + mod.String()
+	if data, ok := cache.Get(key); ok {
+		var lic License
+		if err := json.Unmarshal([]byte(data), &lic); err == nil {
+			lic.ID = canonicalLicense(lic.ID) // cached before the rename
+			return lic, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	lic, err := detectLicense(ctx, mod, repoPath)
+	if err != nil {
+		return License{}, err
+	}
+
+	data, err := json.Marshal(lic)
+	if err == nil {
+		cache.Set(key, string(data))
+	}
+	return lic, nil
+}
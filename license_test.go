@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+func licenseText(t *testing.T, id string) string {
+	t.Helper()
+
+	data, err := licensesFS.ReadFile("licenses/" + id + ".txt")
+	if err != nil {
+		t.Fatalf("read %s: %v", id, err)
+	}
+	return string(data)
+}
+
+func Test_classifyLicense(t *testing.T) {
+	for _, tmpl := range licenseTemplates {
+		t.Run(tmpl.id, func(t *testing.T) {
+			// Reflow and add copyright, like real license files
+			text := "Copyright (c) 2024 Some Author\n\n" + strings.Join(strings.Fields(licenseText(t, tmpl.id)), " ")
+			id, score := classifyLicense(text)
+			if id != tmpl.id {
+				t.Fatalf("expected %s, got %q (%.2f)", tmpl.id, id, score)
+			}
+			if score < minLicenseConfidence {
+				t.Fatalf("low score: %.2f", score)
+			}
+		})
+	}
+}
+
+func Test_canonicalLicense(t *testing.T) {
+	cases := map[string]string{
+		"GPL-3.0":      "GPL-3.0-only",
+		"lgpl-2.1+":    "LGPL-2.1-or-later",
+		"GPL-2.0-only": "GPL-2.0-only",
+		"MIT":          "MIT",
+	}
+	for id, want := range cases {
+		if got := canonicalLicense(id); got != want {
+			t.Errorf("%s: expected %s, got %s", id, want, got)
+		}
+	}
+
+	for _, tmpl := range licenseTemplates {
+		if canonicalLicense(tmpl.id) != tmpl.id {
+			t.Errorf("%s: deprecated SPDX ID", tmpl.id)
+		}
+	}
+}
+
+func Test_classifyLicenseNoAppendix(t *testing.T) {
+	// Most Apache-2.0 LICENSE files end with the terms.
+	text, _, _ := strings.Cut(licenseText(t, "Apache-2.0"), "APPENDIX")
+	id, score := classifyLicense(text)
+	if id != "Apache-2.0" {
+		t.Fatalf("expected Apache-2.0, got %q (%.2f)", id, score)
+	}
+	if score > 0.99 {
+		t.Fatalf("expected partial score, got %.2f", score)
+	}
+}
+
+func Test_classifyLicenseUnknown(t *testing.T) {
+	id, _ := classifyLicense("All rights reserved. You may not use this software in any way.")
+	if id != "" {
+		t.Fatalf("expected no license, got %q", id)
+	}
+}
+
+var gitRefCases = []struct {
+	mod module.Version
+	ref string
+	dir string
+}{
+	{module.Version{Path: "github.com/a/b", Version: "v1.2.3"}, "v1.2.3", ""},
+	{module.Version{Path: "github.com/a/b/v2", Version: "v2.0.1"}, "v2.0.1", ""},
+	{module.Version{Path: "github.com/a/b/sub/v3", Version: "v3.1.0"}, "sub/v3.1.0", "sub"},
+	{module.Version{Path: "github.com/a/b", Version: "v4.0.0+incompatible"}, "v4.0.0", ""},
+	{module.Version{Path: "github.com/a/b", Version: "v0.0.0-20240101000000-abcdef123456"}, "abcdef123456", ""},
+}
+
+func Test_gitRef(t *testing.T) {
+	for _, tc := range gitRefCases {
+		ref, dir := gitRef("github.com/a/b", tc.mod)
+		if ref != tc.ref || dir != tc.dir {
+			t.Errorf("%s: expected %q %q, got %q %q", tc.mod, tc.ref, tc.dir, ref, dir)
+		}
+	}
+}
+
+func Test_detectLicenseFile(t *testing.T) {
+	mit := licenseText(t, "MIT")
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/apple/a/license":
+			t.Errorf("GitHub license API called with a known license file")
+		case "/apple/a/v1.2.3/COPYING":
+			_, _ = w.Write([]byte(mit))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer restore()
+
+	ctx, cancel := testCtx(t)
+	defer cancel()
+
+	lic, err := detectLicense(ctx, module.Version{Path: "github.com/apple/a", Version: "v1.2.3"}, "github.com/apple/a")
+	if err != nil {
+		t.Fatalf("detectLicense: %v", err)
+	}
+	if lic.ID != "MIT" || lic.Source != "COPYING" || lic.Confidence < 0.99 {
+		t.Fatalf("unexpected license: %+v", lic)
+	}
+}
+
+func Test_detectLicenseGitHub(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/apple/a/license" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"license": {"spdx_id": "Apache-2.0"}}`))
+	})
+	defer restore()
+
+	cache := mapCache{m: make(map[string]string)}
+	mod := module.Version{Path: "github.com/apple/a", Version: "v1.2.3"}
+	lic, err := moduleLicense(t.Context(), mod, "github.com/apple/a", cache)
+	if err != nil {
+		t.Fatalf("moduleLicense: %v", err)
+	}
+	if lic.ID != "Apache-2.0" || lic.Source != "github" {
+		t.Fatalf("unexpected license: %+v", lic)
+	}
+
+	restore()
+	// Should come from cache now
+	lic, err = moduleLicense(t.Context(), mod, "github.com/apple/a", cache)
+	if err != nil || lic.ID != "Apache-2.0" {
+		t.Fatalf("cached: %+v, %v", lic, err)
+	}
+}
+
+func Test_zipLicense(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"example.com/x@v1.0.0/go.mod":  "module example.com/x\n",
+		"example.com/x@v1.0.0/LICENSE": licenseText(t, "BSD-3-Clause"),
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+
+	setupProxy(t, map[string]string{
+		"/example.com/x/@v/v1.0.0.zip": buf.String(),
+	})
+
+	ctx, cancel := testCtx(t)
+	defer cancel()
+
+	lic, err := detectLicense(ctx, module.Version{Path: "example.com/x", Version: "v1.0.0"}, "")
+	if err != nil {
+		t.Fatalf("detectLicense: %v", err)
+	}
+	if lic.ID != "BSD-3-Clause" || lic.Source != "LICENSE" {
+		t.Fatalf("unexpected license: %+v", lic)
+	}
+}
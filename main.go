@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -13,14 +14,14 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
+	"path/filepath"
 	"runtime/debug"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/mattn/go-isatty"
-	"golang.org/x/mod/modfile"
 )
 
 var (
@@ -29,15 +30,32 @@ var (
 	httpTimeout = 30 * time.Second
 	repoName    string
 	serveAddr   string
-	httpClient  = http.DefaultClient
+	recursive   bool
+	// keepUnresolved keeps dependencies without repository info.
+	keepUnresolved bool
+	httpClient     = http.DefaultClient
 )
 
 // PkgInfo holds the info for a single dependency.
 type PkgInfo struct {
-	Name    string
-	Version string
-	Desc    string
-	URL     string
+	Name     string
+	Version  string
+	Desc     string
+	URL      string
+	Indirect bool     `json:",omitempty"`
+	Replace  string   `json:",omitempty"` // replacement module or directory
+	Weight   *Weight  `json:",omitempty"`
+	License  *License `json:",omitempty"`
+	Vulns    []Vuln   `json:",omitempty"`
+
+	Deprecated *Deprecation `json:",omitempty"`
+	Retracted  *Retraction  `json:",omitempty"`
+	Go         *GoCompat    `json:",omitempty"`
+	Usage      *Usage       `json:",omitempty"`
+	Changes    *Changes     `json:",omitempty"` // changes up to the latest version
+	Health     *Health      `json:",omitempty"`
+	Warnings   []Warning    `json:",omitempty"` // supply chain warnings
+	Fork       *Fork        `json:",omitempty"`
 }
 
 type repoCache interface {
@@ -51,7 +69,8 @@ func (c mapCache) Get(key string) (string, bool) { v, ok := c.m[key]; return v,
 func (c mapCache) Set(key, value string)         { c.m[key] = value }
 
 const (
-	tokenKey = "GITHUB_TOKEN" // #nosec G704 G101
+	tokenKey   = "GITHUB_TOKEN" // #nosec G704 G101
+	maxModSize = 16 * (1 << 20) // go.mod files are limited to 16 MiB
 )
 
 var extraHelp = `
@@ -68,19 +87,75 @@ func main() {
 	flag.DurationVar(&httpTimeout, "timeout", httpTimeout, "HTTP timeout")
 	flag.StringVar(&repoName, "repo", "", "GitHub repository name")
 	flag.StringVar(&serveAddr, "serve", "", "start web server on host:port")
+	flag.StringVar(&configFile, "config", os.Getenv(configEnvKey), "web server config file with name = value lines")
+	flag.DurationVar(&readTimeout, "read-timeout", readTimeout, "web server request read timeout")
+	flag.DurationVar(&writeTimeout, "write-timeout", writeTimeout, "web server response write timeout")
+	flag.DurationVar(&idleTimeout, "idle-timeout", idleTimeout, "web server keep-alive idle timeout")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", shutdownTimeout, "web server time to drain requests on shutdown")
+	flag.Int64Var(&maxFormBytes, "max-form-bytes", maxFormBytes, "web server maximum request body size")
+	flag.TextVar(&logLevel, "log-level", logLevel, "log level (debug, info, warn, error)")
+	flag.StringVar(&tlsCert, "tls-cert", "", "web server TLS certificate file (needs -tls-key)")
+	flag.StringVar(&tlsKey, "tls-key", "", "web server TLS key file (needs -tls-cert)")
+	flag.IntVar(&serverCacheSize, "cache-size", serverCacheSize, "web server cache size in entries")
+	flag.DurationVar(&serverCacheTTL, "cache-ttl", serverCacheTTL, "web server cache entry TTL")
+	flag.StringVar(&serverCacheFile, "cache-file", "", "file to persist the web server cache (default memory only)")
+	flag.StringVar(&warmupFile, "warmup", "", "file with module paths to cache when the web server starts, one per line")
+	flag.Float64Var(&rateLimit, "rate-limit", rateLimit, "web server requests per minute per client (0 disables)")
+	flag.IntVar(&rateBurst, "rate-burst", rateBurst, "web server request burst per client")
+	flag.Float64Var(&badgeRateLimit, "badge-rate-limit", badgeRateLimit, "web server badge computations per minute per repository (0 disables)")
+	flag.Var(listFlag{&trustedProxies}, "trusted-proxies", "comma separated proxy IPs or CIDRs whose X-Forwarded-For is used")
+	flag.IntVar(&maxModules, "max-modules", maxModules, "web server maximum modules per request (0 is unlimited)")
+	flag.BoolVar(&recursive, "r", false, "scan directory tree (e.g. ./...) for go.mod files")
+	flag.StringVar(&graphFormat, "graph", "", fmt.Sprintf("print dependency graph (%s, %s)", graphDOT, graphMermaid))
+	flag.StringVar(&outputFormat, "format", outputFormat, fmt.Sprintf("output format (%s)", strings.Join(outputFormats, ", ")))
+	flag.BoolVar(&includeIndirect, "indirect", false, "include indirect dependencies")
+	flag.BoolVar(&detectLicenses, "license", false, "detect dependencies licenses")
+	flag.BoolVar(&checkVulns, "vuln", false, "show known vulnerabilities")
+	flag.BoolVar(&failOnVuln, "fail-on-vuln", false, "exit with error if a dependency has known vulnerabilities (implies -vuln)")
+	flag.StringVar(&vulnDBPath, "vulndb", vulnDBPath, "vulnerability database URL, directory or zip file")
+	flag.BoolVar(&checkDeprecated, "deprecated", false, "show deprecated modules and retracted versions")
+	flag.BoolVar(&checkGoCompat, "go-compat", false, "show go and toolchain versions dependencies require")
+	flag.BoolVar(&showUsage, "used", false, "show which packages of the main module import each dependency (local go.mod only)")
+	flag.BoolVar(&showChanges, "changes", false, "show releases between the pinned and latest version")
+	flag.BoolVar(&checkHealth, "health", false, "show maintainer activity and health score")
+	flag.DurationVar(&healthTTL, "health-ttl", healthTTL, "cache TTL of health and fork data")
+	flag.BoolVar(&checkForks, "forks", false, "show upstream of dependencies which are GitHub forks")
+	flag.BoolVar(&checkTyposquat, "typosquat", false, "warn about look-alike module paths and new repositories")
+	flag.StringVar(&allowlistFile, "allowlist", "", "file with trusted module paths, one per line (for -typosquat)")
+	flag.BoolVar(&checkPolicy, "check", false, "exit with error on policy violations")
+	flag.Var(listFlag{&policy.AllowLicenses}, "allow-license", "comma separated allowed licenses (SPDX IDs)")
+	flag.Var(listFlag{&policy.DenyLicenses}, "deny-license", "comma separated denied licenses (SPDX IDs)")
+	flag.BoolVar(&policy.DenyDeprecated, "deny-deprecated", false, "deny deprecated modules (implies -deprecated)")
+	flag.BoolVar(&policy.DenyRetracted, "deny-retracted", false, "deny retracted versions (implies -deprecated)")
+	flag.BoolVar(&showWeight, "weight", false, "show transitive dependency weight")
+	flag.StringVar(&sortBy, "sort", sortBy, fmt.Sprintf("sort order (%s, %s)", sortName, sortWeight))
+	flag.StringVar(&inputFormat, "input-format", inputFormat, fmt.Sprintf("input format (%s)", strings.Join(inputFormats, ", ")))
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "usage: %s [options] [file or URL]\nOptions:\n", exe)
+		fmt.Fprintf(os.Stderr, "usage: %s [options] [file, go.work or URL]\n", exe)
+		fmt.Fprintf(os.Stderr, "       %s [options] changes module[@version] [version]\nOptions:\n", exe)
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, extraHelp, tokenKey)
 	}
 	flag.Parse()
 
+	if err := applyConfig(flag.CommandLine, configFile); err != nil {
+		fmt.Fprintf(os.Stderr, "error: config: %s\n", err)
+		os.Exit(1)
+	}
+	slog.SetLogLoggerLevel(logLevel)
+
 	if showVersion {
 		version := buildVersion()
 		fmt.Printf("%s version %s\n", exe, version)
 		os.Exit(0)
 	}
 
+	// The web server runs the same checks.
+	if err := setupChecks(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
 	if serveAddr != "" {
 		serve(serveAddr)
 		return
@@ -96,6 +171,35 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Interrupt stops the lookups, what was resolved so far is still shown.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// A single "changes" argument is an input file.
+	if flag.Arg(0) == "changes" && flag.NArg() > 1 {
+		// Allow options after the command.
+		if err := flag.CommandLine.Parse(flag.Args()[1:]); err != nil {
+			os.Exit(2)
+		}
+		if flag.NArg() < 1 || flag.NArg() > 2 {
+			fmt.Fprintf(os.Stderr, "error: changes needs a module and an optional version\n")
+			os.Exit(1)
+		}
+
+		cache, err := loadCache()
+		if err != nil {
+			cache = make(map[string]string)
+		}
+		if err := runChanges(ctx, os.Stdout, flag.Arg(0), flag.Arg(1), mapCache{m: cache}); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		if err := saveCache(cache); err != nil {
+			slog.Warn("can't save cache", "error", err)
+		}
+		return
+	}
+
 	if flag.NArg() > 1 {
 		fmt.Fprintf(os.Stderr, "error: too many arguments\n")
 		os.Exit(1)
@@ -106,6 +210,91 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := checkOutputFormat(outputFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+	if isSBOM(outputFormat) {
+		// SBOMs list every module
+		includeIndirect, keepUnresolved = true, true
+	}
+
+	cache, err := loadCache()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("can't load cache", "error", err)
+		}
+		cache = make(map[string]string)
+	}
+
+	if recursive {
+		if repoName != "" {
+			fmt.Fprintf(os.Stderr, "error: can't use -r with -repo\n")
+			os.Exit(1)
+		}
+
+		root := "."
+		if flag.NArg() == 1 {
+			root = scanRoot(flag.Arg(0))
+		}
+
+		report, err := scanInfo(ctx, root, mapCache{m: cache})
+		interrupted := errors.Is(err, context.Canceled)
+		if err != nil && !interrupted {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		// A second interrupt kills the program.
+		stop()
+		if interrupted {
+			fmt.Fprintf(os.Stderr, "interrupted: showing %d scanned modules\n", len(report.Modules))
+		}
+		if outputFormat == formatText {
+			displayScan(os.Stdout, report)
+		} else if err := writeShared(os.Stdout, root, report.Deps, report, outputFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err := saveCache(cache); err != nil {
+			slog.Warn("can't save cache", "error", err)
+		}
+		if interrupted {
+			os.Exit(130)
+		}
+		return
+	}
+
+	if flag.NArg() == 1 && isWorkFile(flag.Arg(0)) {
+		deps, err := workspaceInfo(ctx, flag.Arg(0), mapCache{m: cache})
+		interrupted := errors.Is(err, context.Canceled)
+		if err != nil && !interrupted {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		// A second interrupt kills the program.
+		stop()
+		if interrupted {
+			fmt.Fprintf(os.Stderr, "interrupted: showing %d resolved dependencies\n", len(deps))
+		}
+		if outputFormat == formatText {
+			for _, d := range deps {
+				displayWorkspaceDep(os.Stdout, d)
+			}
+		} else if err := writeShared(os.Stdout, flag.Arg(0), deps, deps, outputFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		if err := saveCache(cache); err != nil {
+			slog.Warn("can't save cache", "error", err)
+		}
+		if interrupted {
+			os.Exit(130)
+		}
+		return
+	}
+
 	var r io.ReadCloser = os.Stdin
 	if flag.NArg() == 1 || repoName != "" {
 		var uri string
@@ -117,7 +306,7 @@ func main() {
 
 		var err error
 		if strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "http://") {
-			r, err = openURL(uri)
+			r, err = openURL(ctx, uri)
 		} else {
 			r, err = os.Open(flag.Arg(0))
 		}
@@ -129,108 +318,495 @@ func main() {
 		defer r.Close()
 	}
 
-	cache, err := loadCache()
-	if err != nil {
-		if !errors.Is(err, fs.ErrNotExist) {
-			slog.Warn("can't load cache", "error", err)
+	if graphFormat != "" {
+		if err := printGraph(ctx, r, mapCache{m: cache}); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
 		}
-		cache = make(map[string]string)
+
+		if err := saveCache(cache); err != nil {
+			slog.Warn("can't save cache", "error", err)
+		}
+		return
 	}
 
-	pkgs, err := pkgsInfo(r, mapCache{m: cache})
+	data, err := io.ReadAll(io.LimitReader(r, maxModSize))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
 		os.Exit(1)
 	}
-	for _, p := range pkgs {
-		displayInfo(p.Name, p.Version, p.Desc)
+
+	pkgs, err := pkgsInfo(ctx, bytes.NewReader(data), mapCache{m: cache})
+	interrupted := errors.Is(err, context.Canceled)
+	if err != nil && !interrupted {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+	// A second interrupt kills the program.
+	stop()
+	if interrupted {
+		fmt.Fprintf(os.Stderr, "interrupted: showing %d resolved dependencies\n", len(pkgs))
+	}
+
+	if showUsage {
+		if repoName != "" || strings.HasPrefix(flag.Arg(0), "https://") || strings.HasPrefix(flag.Arg(0), "http://") {
+			fmt.Fprintf(os.Stderr, "error: -used needs a local go.mod\n")
+			os.Exit(1)
+		}
+
+		dir := "."
+		if flag.NArg() == 1 {
+			dir = filepath.Dir(flag.Arg(0))
+		}
+		imports, err := scanImports(dir, mainModule(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		// Usage covers unresolved requirements as well.
+		reqs, err := parseRequires(data, inputFormat, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		pkgs = addUsage(pkgs, reqs, imports)
+	}
+
+	if (showWeight || sortBy == sortWeight) && !interrupted {
+		g, err := buildGraph(ctx, data, maxGraphNodes, mapCache{m: cache})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		addWeights(pkgs, g)
+	}
+
+	if err := sortPkgs(pkgs, sortBy); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := writePkgs(os.Stdout, mainModule(data), pkgs, outputFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
 	}
 
 	if err := saveCache(cache); err != nil {
 		slog.Warn("can't save cache", "error", err)
 	}
+
+	if violations := policy.Check(pkgs); len(violations) > 0 {
+		displayViolations(os.Stderr, violations)
+		if checkPolicy {
+			os.Exit(1)
+		}
+	}
+
+	if interrupted {
+		os.Exit(130)
+	}
 }
 
-func pkgsInfo(r io.Reader, cache repoCache) ([]PkgInfo, error) {
-	const maxSize = 16 * (1 << 20) // go.mod files are limited to 16 MiB
-	data, err := io.ReadAll(io.LimitReader(r, maxSize))
-	if err != nil {
+// pkgsInfo describes the requirements in r.
+// If ctx is done, it returns the packages described so far with ctx error.
+func pkgsInfo(ctx context.Context, r io.Reader, cache repoCache) ([]PkgInfo, error) {
+	var infos []PkgInfo
+	err := walkPkgsInfo(ctx, r, cache, func(p *PkgInfo, n, total int) error {
+		if p != nil {
+			infos = append(infos, *p)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
 		return nil, err
 	}
+	return infos, err
+}
 
-	f, err := modfile.ParseLax("go.mod", data, nil)
+// walkPkgsInfo describes the requirements in r and calls fn after each one,
+// p is nil for skipped unresolved requirements, n of total are done.
+// It stops when ctx is done or fn returns an error.
+func walkPkgsInfo(ctx context.Context, r io.Reader, cache repoCache, fn func(p *PkgInfo, n, total int) error) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxModSize))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	sort.Slice(f.Require, func(i, j int) bool {
-		return f.Require[i].Mod.Path < f.Require[j].Mod.Path
-	})
+	reqs, err := parseRequires(data, inputFormat, includeIndirect)
+	if err != nil {
+		return err
+	}
 
-	var infos []PkgInfo
-	for _, require := range f.Require {
-		if require.Indirect {
-			continue
-		}
-
-		pkg := require.Mod.Path
-		pkgName := pkg // for proxy
-		if !strings.HasPrefix(pkg, "github.com") {
-			if resolved, ok := cache.Get(pkgName); ok {
-				pkg = resolved
-			} else {
-				ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
-				var err error
-				pkg, err = proxyRepo(ctx, pkg)
-				cancel()
-				if err != nil {
-					infos = append(infos, PkgInfo{Name: pkgName, Version: require.Mod.Version, Desc: fmt.Sprintf("error: %s", err)})
-					continue
-				}
-				cache.Set(pkgName, pkg)
+	var mainGo string
+	if checkGoCompat {
+		mainGo = mainGoVersion(data)
+	}
+
+	for i, req := range reqs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, ok, err := reqInfo(ctx, req, mainGo, cache)
+		if ctx.Err() != nil {
+			// Lookups failed half way, don't report them.
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+
+		var p *PkgInfo
+		if ok {
+			p = &info
+		}
+		if err := fn(p, i+1, len(reqs)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setupChecks turns on the checks implied by other flags, and opens the
+// typosquat allowlist and the vulnerability database.
+func setupChecks() error {
+	if policy.needsLicenses() {
+		detectLicenses = true
+	}
+	if policy.DenyDeprecated || policy.DenyRetracted {
+		checkDeprecated = true
+	}
+	if failOnVuln {
+		checkVulns, checkPolicy, policy.DenyVulns = true, true, true
+	}
+	if checkTyposquat {
+		c, err := newSquatChecker(allowlistFile)
+		if err != nil {
+			return fmt.Errorf("allowlist: %w", err)
+		}
+		squats, policy.DenyWarnings = c, true
+	}
+	if checkVulns {
+		db, err := openVulnDB(vulnDBPath)
+		if err != nil {
+			return fmt.Errorf("vulndb: %w", err)
+		}
+		vulnDatabase = db
+	}
+	return nil
+}
+
+// reqInfo describes a requirement, ok is false for unresolved requirements
+// which should be skipped.
+// mainGo is the go version of the main module.
+func reqInfo(ctx context.Context, req requirement, mainGo string, cache repoCache) (PkgInfo, bool, error) {
+	var err error
+	mod := req.Mod
+	// Describe the replacement, unless it's a local directory.
+	if req.Replace != nil && req.Replace.Version != "" {
+		mod = *req.Replace
+	}
+
+	var warnings []Warning
+	if squats != nil {
+		warnings = squats.Check(req.Mod.Path)
+	}
+
+	info, ok := depInfo(ctx, mod.Path, mod.Version, cache)
+	// Unresolved look-alikes are still reported.
+	if !ok && !keepUnresolved && len(warnings) == 0 {
+		return PkgInfo{}, false, nil
+	}
+	info.Name, info.Version, info.Indirect = req.Mod.Path, req.Mod.Version, req.Indirect
+	if req.Replace != nil {
+		info.Replace = req.Replace.String()
+	}
+
+	if vulnDatabase != nil && mod.Version != "" {
+		ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+		info.Vulns, err = vulnDatabase.Vulns(ctx, mod.Path, mod.Version)
+		cancel()
+		if err != nil {
+			return PkgInfo{}, false, fmt.Errorf("vulndb: %w", err)
+		}
+	}
+
+	if checkDeprecated && mod.Version != "" {
+		info.Deprecated, info.Retracted, err = moduleStatus(ctx, mod, cache)
+		if err != nil {
+			slog.Warn("can't check deprecation", "module", mod, "error", err)
+		}
+	}
+
+	if checkGoCompat && mod.Version != "" {
+		info.Go, err = goCompat(ctx, mod, mainGo, cache)
+		if err != nil {
+			slog.Warn("can't check go version", "module", mod, "error", err)
+		}
+	}
+
+	if showChanges && mod.Version != "" {
+		info.Changes, err = depChanges(ctx, mod, "", strings.TrimPrefix(info.URL, "https://"), cache)
+		if err != nil {
+			slog.Warn("can't get changes", "module", mod, "error", err)
+		}
+	}
+
+	if squats != nil {
+		info.Warnings = warnings
+		if owner, repo := repoInfo(strings.TrimPrefix(info.URL, "https://")); owner != "" {
+			created, err := repoCreated(ctx, owner, repo, cache)
+			if err != nil {
+				slog.Warn("can't get repository creation time", "module", mod, "error", err)
+			} else if w := newRepoWarning(created, time.Now()); w != nil {
+				info.Warnings = append(info.Warnings, *w)
 			}
 		}
+	}
+
+	if owner, repo := repoInfo(strings.TrimPrefix(info.URL, "https://")); checkForks && owner != "" {
+		info.Fork, err = depFork(ctx, owner, repo, cache)
+		if err != nil {
+			slog.Warn("can't check fork", "module", mod, "error", err)
+		}
+	}
 
-		owner, repo := repoInfo(pkg)
-		if owner == "" || repo == "" {
-			slog.Warn("can't get info", "package", pkg)
-			continue
+	if owner, repo := repoInfo(strings.TrimPrefix(info.URL, "https://")); checkHealth && owner != "" {
+		h, err := depHealth(ctx, owner, repo, cache)
+		if err != nil {
+			slog.Warn("can't get health", "module", mod, "error", err)
+		} else {
+			info.Health = &h
 		}
+	}
 
-		key := fmt.Sprintf("%s/%s", owner, repo)
-		desc, ok := cache.Get(key)
-		if !ok {
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	if detectLicenses && mod.Version != "" {
+		lic, err := moduleLicense(ctx, mod, strings.TrimPrefix(info.URL, "https://"), cache)
+		if err != nil {
+			slog.Warn("can't detect license", "module", mod, "error", err)
+		} else {
+			info.License = &lic
+		}
+	}
+	return info, true, nil
+}
+
+func printGraph(ctx context.Context, r io.Reader, cache repoCache) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxModSize))
+	if err != nil {
+		return err
+	}
+
+	g, err := buildGraph(ctx, data, maxGraphNodes, cache)
+	if err != nil {
+		return err
+	}
+	describeGraph(ctx, g, cache)
+
+	return writeGraph(os.Stdout, g, graphFormat)
+}
+
+// depInfo returns the info for a single dependency, ok is false if the
+// dependency should be skipped (info will have only name and version).
+func depInfo(ctx context.Context, pkgName, version string, cache repoCache) (PkgInfo, bool) {
+	pkg := pkgName
+	if !strings.HasPrefix(pkg, "github.com") {
+		if resolved, ok := cache.Get(pkgName); ok {
+			pkg = resolved
+		} else {
 			var err error
-			desc, err = repoDesc(ctx, owner, repo)
-			cancel()
+			pkg, _, err = repoFlights.Do(ctx, pkgName, func(ctx context.Context) (string, error) {
+				ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+				defer cancel()
+				return proxyRepo(ctx, pkgName)
+			})
 			if err != nil {
-				slog.Error("can't get description", "package", pkgName, "repo", pkg, "error", err)
-				continue
+				return PkgInfo{Name: pkgName, Version: version, Desc: fmt.Sprintf("error: %s", err)}, true
 			}
-			cache.Set(key, desc)
+			cache.Set(pkgName, pkg)
 		}
+	}
+
+	owner, repo := repoInfo(pkg)
+	if owner == "" || repo == "" {
+		slog.Warn("can't get info", "package", pkg)
+		return PkgInfo{Name: pkgName, Version: version}, false
+	}
 
-		infos = append(infos, PkgInfo{Name: pkgName, Version: require.Mod.Version, Desc: desc, URL: fmt.Sprintf("https://github.com/%s/%s", owner, repo)})
+	key := fmt.Sprintf("%s/%s", owner, repo)
+	desc, ok := cache.Get(key)
+	if !ok {
+		var err error
+		desc, _, err = descFlights.Do(ctx, key, func(ctx context.Context) (string, error) {
+			ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+			defer cancel()
+			return repoDesc(ctx, owner, repo, cache)
+		})
+		if err != nil {
+			slog.Error("can't get description", "package", pkgName, "repo", pkg, "error", err)
+			return PkgInfo{Name: pkgName, Version: version}, false
+		}
+		cache.Set(key, desc)
 	}
 
-	return infos, nil
+	return PkgInfo{Name: pkgName, Version: version, Desc: desc, URL: fmt.Sprintf("https://github.com/%s/%s", owner, repo)}, true
 }
 
 var (
-	pkgFormat string
+	pkgFormat  string
+	warnFormat string
 )
 
 func init() {
 	if isatty.IsTerminal(os.Stdout.Fd()) {
 		pkgFormat = "\033[1m%s\033[0m \033[3m%s\033[0m:\n\t%s\n"
+		warnFormat = "\033[31m%s\033[0m"
 	} else {
 		pkgFormat = "%s %s:\n\t%s\n"
+		warnFormat = "%s"
+	}
+}
+
+func displayInfo(w io.Writer, pkg, version, desc string) {
+	fmt.Fprintf(w, pkgFormat, pkg, version, desc)
+}
+
+// goRequirement returns a go directive with its toolchain, e.g. "1.21 (toolchain go1.22.3)".
+func goRequirement(goVersion, toolchain string) string {
+	if goVersion == "" {
+		goVersion = "unknown"
+	}
+	if toolchain != "" {
+		goVersion += fmt.Sprintf(" (toolchain %s)", toolchain)
+	}
+	return goVersion
+}
+
+func displayGoCompat(w io.Writer, c *GoCompat) {
+	line := "go " + goRequirement(c.Go, c.Toolchain)
+	if c.TooNew {
+		line = fmt.Sprintf(warnFormat, line+", newer than main module")
+	}
+	fmt.Fprintf(w, "\t%s\n", line)
+
+	if c.Latest == "" || (c.LatestGo == c.Go && c.LatestToolchain == c.Toolchain) {
+		return
+	}
+	line = fmt.Sprintf("latest %s: go %s", c.Latest, goRequirement(c.LatestGo, c.LatestToolchain))
+	if c.LatestTooNew {
+		line = fmt.Sprintf(warnFormat, line+", upgrade forces go bump")
+	}
+	fmt.Fprintf(w, "\t%s\n", line)
+}
+
+func displayPkg(w io.Writer, p PkgInfo) {
+	version := p.Version
+	if p.Replace != "" {
+		version += " => " + p.Replace
+	}
+	if p.Indirect {
+		version += " // indirect"
+	}
+	displayInfo(w, p.Name, version, p.Desc)
+	for _, warn := range p.Warnings {
+		fmt.Fprintf(w, "\t"+warnFormat+": %s\n", "WARNING", warn)
+	}
+	if d := p.Deprecated; d != nil {
+		fmt.Fprintf(w, "\t"+warnFormat+": %s\n", "DEPRECATED", d.Message)
+		if d.Replacement != "" {
+			fmt.Fprintf(w, "\treplacement: %s\n", d.Replacement)
+		}
+	}
+	if r := p.Retracted; r != nil {
+		rationale := r.Rationale
+		if rationale == "" {
+			rationale = "no rationale given"
+		}
+		fmt.Fprintf(w, "\t"+warnFormat+": %s (latest %s)\n", "RETRACTED", rationale, r.Latest)
+	}
+	if p.Go != nil {
+		displayGoCompat(w, p.Go)
+	}
+	if p.License != nil {
+		fmt.Fprintf(w, "\tlicense: %s (%s, %.0f%%)\n", p.License.ID, p.License.Source, p.License.Confidence*100)
+	}
+	for _, v := range p.Vulns {
+		fixed := ""
+		if v.Fixed != "" {
+			fixed = fmt.Sprintf(" (fixed in %s)", v.Fixed)
+		}
+		fmt.Fprintf(w, "\t"+warnFormat+": %s%s\n", v.ID, v.Summary, fixed)
+	}
+	if f := p.Fork; f != nil {
+		line := f.String()
+		if f.Behind > 0 && f.UpstreamMoreActive {
+			line = fmt.Sprintf(warnFormat, line)
+		}
+		fmt.Fprintf(w, "\t%s\n", line)
+	}
+	if h := p.Health; h != nil {
+		score := h.String()
+		if h.Low() {
+			score = fmt.Sprintf(warnFormat, score)
+		}
+		fmt.Fprintf(w, "\thealth: %s\n\t\t%s\n", score, h.Activity())
+	}
+	if p.Changes != nil {
+		fmt.Fprintf(w, "\tchanges up to %s:\n", p.Changes.To)
+		displayChanges(w, p.Changes, "\t\t", false)
+	}
+	if u := p.Usage; u != nil {
+		if len(u.Packages) == 0 {
+			fmt.Fprintf(w, "\t"+warnFormat+"\n", "unused: candidate for go mod tidy or removal")
+		} else {
+			fmt.Fprintf(w, "\tused by: %s (%d imports)\n", strings.Join(u.Packages, ", "), u.Sites)
+		}
+	}
+	if wt := p.Weight; wt != nil {
+		if wt.Approximate {
+			fmt.Fprintf(w, "\tdeps: at least %d, unique: %d (graph truncated)\n", wt.Total, len(wt.Unique))
+		} else {
+			fmt.Fprintf(w, "\tdeps: %d, unique: %d\n", wt.Total, len(wt.Unique))
+		}
 	}
 }
 
-func displayInfo(pkg, version, desc string) {
-	fmt.Printf(pkgFormat, pkg, version, desc)
+func displayWorkspaceDep(w io.Writer, d WorkspaceDep) {
+	displayInfo(w, d.Name, d.Version, d.Desc)
+	for _, m := range d.Users() {
+		fmt.Fprintf(w, "\t\t%s %s\n", m, d.Versions[m])
+	}
+	if d.Skewed() {
+		fmt.Fprintf(w, "\t"+warnFormat+"\n", "version skew")
+	}
+}
+
+func displayScan(w io.Writer, report ScanReport) {
+	for _, m := range report.Modules {
+		fmt.Fprintf(w, "# %s (%s)\n", m.Path, m.File)
+		for _, p := range m.Pkgs {
+			displayPkg(w, p)
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "# all dependencies")
+	for _, d := range report.Deps {
+		displayWorkspaceDep(w, d)
+	}
+
+	if conflicts := report.Conflicts(); len(conflicts) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "# "+warnFormat+"\n", "version conflicts")
+		for _, d := range conflicts {
+			fmt.Fprintf(w, "%s:", d.Name)
+			for _, m := range d.Users() {
+				fmt.Fprintf(w, " %s@%s", m, d.Versions[m])
+			}
+			fmt.Fprintln(w)
+		}
+	}
 }
 
 func buildVersion() string {
@@ -247,38 +823,67 @@ func buildVersion() string {
 	return version
 }
 
-func repoDesc(ctx context.Context, owner, repo string) (string, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s", githubAPIBase, url.PathEscape(owner), url.PathEscape(repo))
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return "", err
-	}
+type githubRepo struct {
+	FullName      string    `json:"full_name"`
+	Description   string    `json:"description"`
+	Fork          bool      `json:"fork"`
+	Archived      bool      `json:"archived"`
+	OpenIssues    int       `json:"open_issues_count"` // issues + pull requests
+	DefaultBranch string    `json:"default_branch"`
+	CreatedAt     time.Time `json:"created_at"`
+	PushedAt      time.Time `json:"pushed_at"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Parent *githubRepo `json:"parent"`
+}
 
-	token := os.Getenv(tokenKey)
-	if token != "" {
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	}
+// repoEntry is a cached GitHub repository.
+type repoEntry struct {
+	Repo    githubRepo
+	Fetched time.Time
+}
 
-	resp, err := httpClient.Do(req) //#nosec G704
-	if err != nil {
-		return "", err
+// repoMeta returns the GitHub repository information, using the cache.
+// Description, fork, health, archived and creation time checks all read it, so
+// the repository is fetched once. Cached information older than healthTTL is
+// fetched again.
+func repoMeta(ctx context.Context, owner, repo string, cache repoCache) (githubRepo, error) {
+	key := fmt.Sprintf("repo:%s/%s", owner, repo)
+	if data, ok := cache.Get(key); ok {
+		var e repoEntry
+		if err := json.Unmarshal([]byte(data), &e); err == nil && time.Since(e.Fetched) < healthTTL {
+			return e.Repo, nil
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%q: %s", url, resp.Status)
+	r, _, err := metaFlights.Do(ctx, owner+"/"+repo, func(ctx context.Context) (githubRepo, error) {
+		ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+		defer cancel()
+
+		var r githubRepo
+		err := githubGet(ctx, fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo)), &r)
+		return r, err
+	})
+	if err != nil {
+		return githubRepo{}, err
 	}
 
-	var reply struct {
-		Description string
+	data, err := json.Marshal(repoEntry{Repo: r, Fetched: time.Now()})
+	if err == nil {
+		cache.Set(key, string(data))
 	}
+	return r, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
-		return "", fmt.Errorf("%q: can't decode JSON - %w", url, err)
+func repoDesc(ctx context.Context, owner, repo string, cache repoCache) (string, error) {
+	r, err := repoMeta(ctx, owner, repo, cache)
+	if err != nil {
+		return "", err
 	}
 
-	if reply.Description != "" {
-		return reply.Description, nil
+	if r.Description != "" {
+		return r.Description, nil
 	}
 
 	desc, err := readmeDesc(ctx, owner, repo)
@@ -373,7 +978,7 @@ func githubRawURL(ghURL string) (string, error) {
 	return u.String(), nil
 }
 
-func openURL(rawURL string) (io.ReadCloser, error) {
+func openURL(ctx context.Context, rawURL string) (io.ReadCloser, error) {
 	parsed, err := url.Parse(rawURL)
 	if err != nil {
 		return nil, fmt.Errorf("%q: bad URL- %w", rawURL, err)
@@ -392,7 +997,7 @@ func openURL(rawURL string) (io.ReadCloser, error) {
 		}
 	}
 
-	req, err := http.NewRequest(http.MethodGet, rawURL, nil) //#nosec G704
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil) //#nosec G704
 	if err != nil {
 		return nil, fmt.Errorf("%q: bad URL- %w", rawURL, err)
 	}
@@ -67,7 +67,7 @@ func Test_repoDesc(t *testing.T) {
 	ctx, cancel := testCtx(t)
 	defer cancel()
 
-	desc, err := repoDesc(ctx, "pkg", "errors")
+	desc, err := repoDesc(ctx, "pkg", "errors", mapCache{m: make(map[string]string)})
 	if err != nil {
 		t.Fatalf("API: %v", err)
 	}
@@ -78,6 +78,46 @@ func Test_repoDesc(t *testing.T) {
 	}
 }
 
+func Test_repoMetaOnce(t *testing.T) {
+	calls := 0
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/apple/a":
+			calls++
+			_, _ = io.WriteString(w, `{"description": "apples", "archived": true, "created_at": "2026-01-02T00:00:00Z"}`)
+		case "/repos/apple/a/releases/latest":
+			http.NotFound(w, r)
+		default:
+			_, _ = io.WriteString(w, `[]`)
+		}
+	})
+	defer restore()
+
+	ctx, cancel := testCtx(t)
+	defer cancel()
+
+	cache := mapCache{m: make(map[string]string)}
+	if desc, err := repoDesc(ctx, "apple", "a", cache); err != nil || desc != "apples" {
+		t.Fatalf("repoDesc: %q, %v", desc, err)
+	}
+	if created, err := repoCreated(ctx, "apple", "a", cache); err != nil || created.Year() != 2026 {
+		t.Fatalf("repoCreated: %v, %v", created, err)
+	}
+	if archived, err := repoArchived(ctx, "apple", "a", cache); err != nil || !archived {
+		t.Fatalf("repoArchived: %v, %v", archived, err)
+	}
+	if f, err := depFork(ctx, "apple", "a", cache); err != nil || f != nil {
+		t.Fatalf("depFork: %+v, %v", f, err)
+	}
+	if h, err := depHealth(ctx, "apple", "a", cache); err != nil || !h.Archived {
+		t.Fatalf("depHealth: %s, %v", h, err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected one repository fetch, got %d", calls)
+	}
+}
+
 func Test_repoDescFallbackToReadme(t *testing.T) {
 	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -95,7 +135,7 @@ func Test_repoDescFallbackToReadme(t *testing.T) {
 	ctx, cancel := testCtx(t)
 	defer cancel()
 
-	desc, err := repoDesc(ctx, "bmizerany", "pat")
+	desc, err := repoDesc(ctx, "bmizerany", "pat", mapCache{m: make(map[string]string)})
 	if err != nil {
 		t.Fatalf("repoDesc: %v", err)
 	}
@@ -114,7 +154,7 @@ func Test_repoDescStatusError(t *testing.T) {
 	ctx, cancel := testCtx(t)
 	defer cancel()
 
-	_, err := repoDesc(ctx, "pkg", "errors")
+	_, err := repoDesc(ctx, "pkg", "errors", mapCache{m: make(map[string]string)})
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -273,7 +313,7 @@ func TestGHToken(t *testing.T) {
 
 	ctx, cancel := testCtx(t)
 	defer cancel()
-	repoDesc(ctx, "tebeka", "expmod") // Should err, we don't care - it's a mock
+	repoDesc(ctx, "tebeka", "expmod", mapCache{m: make(map[string]string)}) // Should err, we don't care - it's a mock
 
 	if mt.token != token {
 		t.Fatalf("expected token %q, got %q", token, mt.token)
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Minimal Prometheus text format metrics, see
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+
+// latencyBuckets are histogram buckets in seconds.
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// metric is written in the Prometheus text format.
+type metric interface {
+	write(w io.Writer)
+}
+
+// labelValues joins label values to a map key.
+func labelValues(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+// formatLabels returns {name="value",...} for the key made by labelValues.
+func formatLabels(names []string, key string, extra ...string) string {
+	var pairs []string
+	if len(names) > 0 {
+		for i, v := range strings.Split(key, "\x00") {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", names[i], v))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// counterVec is a counter with labels, also used for gauges.
+type counterVec struct {
+	name, help, kind string
+	labels           []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newCounter(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, kind: "counter", labels: labels, values: make(map[string]float64)}
+}
+
+func newGauge(name, help string, labels ...string) *counterVec {
+	c := newCounter(name, help, labels...)
+	c.kind = "gauge"
+	return c
+}
+
+func (c *counterVec) Add(v float64, labels ...string) {
+	c.mu.Lock()
+	c.values[labelValues(labels)] += v
+	c.mu.Unlock()
+}
+
+func (c *counterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *counterVec) Set(v float64, labels ...string) {
+	c.mu.Lock()
+	c.values[labelValues(labels)] = v
+	c.mu.Unlock()
+}
+
+// Value returns the value for the labels.
+func (c *counterVec) Value(labels ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[labelValues(labels)]
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", c.name, c.help, c.name, c.kind)
+	for _, key := range slices.Sorted(maps.Keys(c.values)) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key), formatFloat(c.values[key]))
+	}
+}
+
+// histogramVec is a histogram with labels.
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, series: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) Observe(v float64, labels ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := labelValues(labels)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range slices.Sorted(maps.Keys(h.series)) {
+		s := h.series[key]
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key), s.count)
+	}
+}
+
+var (
+	httpRequests = newCounter("expmod_http_requests_total", "HTTP requests by route and status code.", "route", "code")
+	httpDuration = newHistogram("expmod_http_request_duration_seconds", "HTTP request latency by route.", latencyBuckets, "route")
+
+	cacheRequests = newCounter("expmod_cache_requests_total", "Cache lookups by result (hit or miss).", "result")
+
+	upstreamRequests = newCounter("expmod_upstream_requests_total", "Upstream calls by service and status code.", "service", "code")
+	upstreamErrors   = newCounter("expmod_upstream_errors_total", "Upstream calls which failed or got a non 2xx status.", "service")
+	upstreamDuration = newHistogram("expmod_upstream_request_duration_seconds", "Upstream call latency by service.", latencyBuckets, "service")
+
+	githubRateRemaining = newGauge("expmod_github_rate_limit_remaining", "Remaining GitHub API requests in the current window.")
+	githubRateReset     = newGauge("expmod_github_rate_limit_reset_timestamp_seconds", "Unix time the GitHub API rate limit window resets.")
+
+	metrics = []metric{
+		httpRequests, httpDuration,
+		cacheRequests,
+		upstreamRequests, upstreamErrors, upstreamDuration,
+		githubRateRemaining, githubRateReset,
+	}
+)
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// statusWriter records the response status code.
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the Flusher of streams.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// instrument records request count and latency per route of the mux.
+func instrument(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		mux.ServeHTTP(sw, r)
+
+		// The mux sets the matched pattern on r.
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		if sw.code == 0 {
+			sw.code = http.StatusOK
+		}
+		httpRequests.Inc(route, strconv.Itoa(sw.code))
+		httpDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// upstreamService returns the service name of an upstream URL.
+func upstreamService(u *url.URL) string {
+	bases := []struct{ name, base string }{
+		{"github", githubAPIBase},
+		{"github_raw", githubRawBase},
+		{"proxy", goProxyBase},
+		{"vulndb", vulnDBPath},
+	}
+	for _, b := range bases {
+		if bu, err := url.Parse(b.base); err == nil && bu.Host == u.Host {
+			return b.name
+		}
+	}
+	return "other"
+}
+
+// metricsTransport records upstream calls and the GitHub rate limit.
+type metricsTransport struct {
+	next http.RoundTripper // nil for http.DefaultTransport
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	service := upstreamService(req.URL)
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	upstreamDuration.Observe(time.Since(start).Seconds(), service)
+	if err != nil {
+		upstreamRequests.Inc(service, "error")
+		upstreamErrors.Inc(service)
+		return nil, err
+	}
+
+	upstreamRequests.Inc(service, strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		upstreamErrors.Inc(service)
+	}
+	if service == "github" {
+		if v, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Remaining"), 64); err == nil {
+			githubRateRemaining.Set(v)
+		}
+		if v, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset"), 64); err == nil {
+			githubRateReset.Set(v)
+		}
+	}
+	return resp, nil
+}
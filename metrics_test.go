@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	c := newCounter("test_total", "Test counter.", "route", "code")
+	c.Inc("/api", "200")
+	c.Inc("/api", "200")
+	c.Inc("/", "429")
+
+	h := newHistogram("test_seconds", "Test histogram.", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "/api")
+	h.Observe(0.5, "/api")
+	h.Observe(5, "/api")
+
+	var buf strings.Builder
+	c.write(&buf)
+	h.write(&buf)
+
+	expected := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{route="/",code="429"} 1
+test_total{route="/api",code="200"} 2
+# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{route="/api",le="0.1"} 1
+test_seconds_bucket{route="/api",le="1"} 2
+test_seconds_bucket{route="/api",le="+Inf"} 3
+test_seconds_sum{route="/api"} 5.55
+test_seconds_count{route="/api"} 3
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	h := instrument(mux)
+
+	before := httpRequests.Value("GET /healthz", "200")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("healthz: status %d", w.Code)
+	}
+	if n := httpRequests.Value("GET /healthz", "200"); n != before+1 {
+		t.Fatalf("expected %v requests, got %v", before+1, n)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `expmod_http_requests_total{route="GET /healthz",code="200"}`) {
+		t.Fatalf("metrics missing request count:\n%s", w.Body.String())
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	srv.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before ready, got %d", w.Code)
+	}
+
+	srv.ready.Store(true)
+	w = httptest.NewRecorder()
+	srv.handleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 when ready, got %d", w.Code)
+	}
+}
+
+func TestMetricsTransport(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		if r.URL.Path != "/repos/pkg/errors" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer restore()
+
+	client := &http.Client{Transport: &metricsTransport{next: httpClient.Transport}}
+	before := upstreamErrors.Value("github")
+	for _, path := range []string{"/repos/pkg/errors", "/missing"} {
+		resp, err := client.Get(githubAPIBase + path)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if n := upstreamErrors.Value("github"); n != before+1 {
+		t.Fatalf("expected %v errors, got %v", before+1, n)
+	}
+	if v := githubRateRemaining.Value(); v != 4321 {
+		t.Fatalf("expected remaining 4321, got %v", v)
+	}
+	if v := githubRateReset.Value(); v != 1700000000 {
+		t.Fatalf("expected reset 1700000000, got %v", v)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Output formats.
+const (
+	formatText      = "text"
+	formatJSON      = "json"
+	formatCycloneDX = "cyclonedx-json"
+	formatSPDX      = "spdx-json"
+	formatMarkdown  = "markdown"
+)
+
+var outputFormats = []string{formatText, formatJSON, formatCycloneDX, formatSPDX, formatMarkdown}
+
+var outputFormat = formatText
+
+func checkOutputFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format %q (valid: %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// isSBOM reports whether format is an SBOM format.
+func isSBOM(format string) bool {
+	return format == formatCycloneDX || format == formatSPDX
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writePkgs writes the dependencies of main in format.
+func writePkgs(w io.Writer, main string, pkgs []PkgInfo, format string) error {
+	if main == "" {
+		main = "main"
+	}
+
+	switch format {
+	case formatText:
+		for _, p := range pkgs {
+			displayPkg(w, p)
+		}
+		return nil
+	case formatJSON:
+		return writeJSON(w, pkgs)
+	case formatCycloneDX:
+		return writeJSON(w, cycloneDX(main, pkgs, time.Now()))
+	case formatSPDX:
+		return writeJSON(w, spdx(main, pkgs, time.Now()))
+	case formatMarkdown:
+		writeMarkdown(w, main, pkgs)
+		return nil
+	}
+	return checkOutputFormat(format)
+}
+
+// markdownEscaper escapes table cell text.
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// writeMarkdown writes the dependencies as a markdown table, followed by
+// their release notes.
+func writeMarkdown(w io.Writer, main string, pkgs []PkgInfo) {
+	fmt.Fprintf(w, "# %s dependencies\n\n", main)
+	fmt.Fprintln(w, "| Module | Version | Description |")
+	fmt.Fprintln(w, "|--------|---------|-------------|")
+	for _, p := range pkgs {
+		name := p.Name
+		if p.URL != "" {
+			name = fmt.Sprintf("[%s](%s)", p.Name, p.URL)
+		}
+		desc := p.Desc
+		for _, warn := range p.Warnings {
+			desc += fmt.Sprintf(" **WARNING %s**", warn)
+		}
+		if d := p.Deprecated; d != nil {
+			desc += " **DEPRECATED** " + d.Message
+			if d.Replacement != "" {
+				desc += fmt.Sprintf(" (use `%s`)", d.Replacement)
+			}
+		}
+		if r := p.Retracted; r != nil {
+			rationale := r.Rationale
+			if rationale == "" {
+				rationale = "no rationale given"
+			}
+			desc += fmt.Sprintf(" **RETRACTED** %s (latest %s)", rationale, r.Latest)
+		}
+		for _, v := range p.Vulns {
+			desc += fmt.Sprintf(" **VULNERABILITY [%s](%s)** %s", v.ID, v.URL(), v.Summary)
+			if v.Fixed != "" {
+				desc += fmt.Sprintf(" (fixed in %s)", v.Fixed)
+			}
+		}
+		fmt.Fprintf(w, "| %s | %s | %s |\n", name, p.Version, markdownEscaper.Replace(desc))
+	}
+	fmt.Fprintln(w)
+
+	for _, p := range pkgs {
+		if p.Changes != nil {
+			writeChangesMarkdown(w, p.Name, p.Changes, "##")
+		}
+	}
+}
+
+// writeShared writes aggregated dependencies (workspace or scan) in format.
+// report is the value written in JSON format.
+func writeShared(w io.Writer, main string, deps []WorkspaceDep, report any, format string) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, report)
+	case formatCycloneDX, formatSPDX, formatMarkdown:
+		pkgs := make([]PkgInfo, len(deps))
+		for i, d := range deps {
+			pkgs[i] = d.PkgInfo
+		}
+		return writePkgs(w, main, pkgs, format)
+	}
+	return checkOutputFormat(format)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Cache-Control of permalinks, HEAD changes more often than tags or pasted
+// content.
+const (
+	headCacheControl  = "public, max-age=300"
+	fixedCacheControl = "public, max-age=3600"
+	errorCacheControl = "no-store"
+)
+
+// pageData is the data for pageTmpl.
+type pageData struct {
+	Repo    string // owner/repo[@ref] in the form
+	Content string // go.mod in the form
+	Results *results
+	Error   string
+}
+
+// parseRepoRef splits "owner/repo@ref", ref is HEAD if missing.
+func parseRepoRef(s string) (repo, ref string, err error) {
+	repo, ref, _ = strings.Cut(s, "@")
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("%q: bad repository, should be owner/repo[@ref]", s)
+	}
+	for _, part := range strings.Split(ref, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", "", fmt.Errorf("%q: bad ref", ref)
+		}
+	}
+	return repo, ref, nil
+}
+
+// repoGoMod returns the go.mod of the GitHub repository at ref.
+func repoGoMod(ctx context.Context, repo, ref string) ([]byte, error) {
+	parts := strings.Split(ref, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+
+	uri := fmt.Sprintf("%s/%s/%s/go.mod", githubRawBase, repo, strings.Join(parts, "/"))
+	rc, err := openURL(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxModSize))
+}
+
+// pasteHash returns the content hash of a pasted go.mod.
+func pasteHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// savePaste stores pasted content and returns its hash.
+func (s *server) savePaste(data []byte) string {
+	hash := pasteHash(data)
+	s.cache.Set("paste:"+hash, string(data))
+	return hash
+}
+
+// paste returns the pasted content of hash.
+func (s *server) paste(hash string) ([]byte, bool) {
+	data, ok := s.cache.Get("paste:" + hash)
+	if !ok {
+		return nil, false
+	}
+	// Viewing a paste keeps it alive.
+	s.cache.Set("paste:"+hash, data)
+	return []byte(data), true
+}
+
+// permalink returns the GET URL of a submitted form, "" if there's none.
+func permalink(r *http.Request) string {
+	if repo := r.FormValue("repo"); repo != "" {
+		return "/r/" + repo
+	}
+	if content := r.FormValue("content"); content != "" {
+		return "/s/" + pasteHash([]byte(content))
+	}
+	return ""
+}
+
+// repoCacheControl returns the Cache-Control of a repository at ref.
+func repoCacheControl(ref string) string {
+	if ref == "HEAD" {
+		return headCacheControl
+	}
+	return fixedCacheControl
+}
+
+// renderPage writes the full page, status is used only for errors.
+func renderPage(w http.ResponseWriter, data pageData, status int) {
+	if data.Error != "" {
+		w.Header().Set("Cache-Control", errorCacheControl)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+	}
+	if err := pageTmpl.Execute(w, data); err != nil {
+		slog.Error("render page", "error", err)
+	}
+}
+
+// handleRepoPage renders the results of a GitHub repository,
+// GET /r/{owner}/{repo}[@ref].
+func (s *server) handleRepoPage(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("owner") + "/" + r.PathValue("repo")
+	page := pageData{Repo: name}
+
+	repo, ref, err := parseRepoRef(name)
+	if err != nil {
+		page.Error = err.Error()
+		renderPage(w, page, http.StatusBadRequest)
+		return
+	}
+
+	data, err := repoGoMod(r.Context(), repo, ref)
+	if err != nil {
+		page.Error = err.Error()
+		renderPage(w, page, http.StatusBadGateway)
+		return
+	}
+	if err := checkModules(data); err != nil {
+		page.Error = err.Error()
+		renderPage(w, page, requestErrorStatus(err))
+		return
+	}
+
+	s.renderResultsPage(w, r, page, data, repoCacheControl(ref))
+}
+
+// handlePastePage renders the results of pasted content, GET /s/{hash}.
+func (s *server) handlePastePage(w http.ResponseWriter, r *http.Request) {
+	data, ok := s.paste(r.PathValue("hash"))
+	if !ok {
+		renderPage(w, pageData{Error: "unknown or expired link, please paste the go.mod again"}, http.StatusNotFound)
+		return
+	}
+
+	s.renderResultsPage(w, r, pageData{Content: string(data)}, data, fixedCacheControl)
+}
+
+func (s *server) renderResultsPage(w http.ResponseWriter, r *http.Request, page pageData, data []byte, cacheControl string) {
+	res, err := s.results(r.Context(), data, r.FormValue("graph") != "")
+	if err != nil {
+		page.Error = err.Error()
+		renderPage(w, page, http.StatusBadRequest)
+		return
+	}
+
+	page.Results = &res
+	w.Header().Set("Cache-Control", cacheControl)
+	renderPage(w, page, http.StatusOK)
+}
+
+// handleRepoAPI returns the dependencies of a GitHub repository as JSON,
+// GET /api/{owner}/{repo}[@ref].
+func (s *server) handleRepoAPI(w http.ResponseWriter, r *http.Request) {
+	fail := func(err error, status int) {
+		w.Header().Set("Cache-Control", errorCacheControl)
+		http.Error(w, err.Error(), status)
+	}
+
+	repo, ref, err := parseRepoRef(r.PathValue("owner") + "/" + r.PathValue("repo"))
+	if err != nil {
+		fail(err, http.StatusBadRequest)
+		return
+	}
+
+	data, err := repoGoMod(r.Context(), repo, ref)
+	if err != nil {
+		fail(err, http.StatusBadGateway)
+		return
+	}
+	if err := checkModules(data); err != nil {
+		fail(err, requestErrorStatus(err))
+		return
+	}
+
+	pkgs, err := pkgsInfo(r.Context(), bytes.NewReader(data), s.cache)
+	if err != nil {
+		fail(err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Cache-Control", repoCacheControl(ref))
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pkgs); err != nil {
+		slog.Error("encode response", "error", err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var repoRefCases = []struct {
+	in   string
+	repo string
+	ref  string
+	err  bool
+}{
+	{"owner/repo", "owner/repo", "HEAD", false},
+	{"owner/repo@v1.2.3", "owner/repo", "v1.2.3", false},
+	{"owner/repo@feature/x", "owner/repo", "feature/x", false},
+	{"owner", "", "", true},
+	{"owner/repo/sub", "", "", true},
+	{"owner/repo@../../x", "", "", true},
+}
+
+func Test_parseRepoRef(t *testing.T) {
+	for _, tc := range repoRefCases {
+		t.Run(tc.in, func(t *testing.T) {
+			repo, ref, err := parseRepoRef(tc.in)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRepoRef: %v", err)
+			}
+			if repo != tc.repo || ref != tc.ref {
+				t.Fatalf("expected %q %q, got %q %q", tc.repo, tc.ref, repo, ref)
+			}
+		})
+	}
+}
+
+// setupRawGoMod serves testGoMod for owner/repo at ref.
+func setupRawGoMod(t *testing.T, ref string) *server {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/owner/repo/%s/go.mod", ref) {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testGoMod))
+	}))
+	t.Cleanup(ts.Close)
+
+	prevBase := githubRawBase
+	githubRawBase = ts.URL
+	t.Cleanup(func() { githubRawBase = prevBase })
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	srv.cache.Set("apple/a", "desc A")
+	srv.cache.Set("banana/b", "desc B")
+	return srv
+}
+
+func TestHandleRepoPage(t *testing.T) {
+	srv := setupRawGoMod(t, "v1.0.0")
+
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/owner/repo@v1.0.0", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d\n%s", w.Code, w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != fixedCacheControl {
+		t.Fatalf("expected Cache-Control %q, got %q", fixedCacheControl, cc)
+	}
+	body := w.Body.String()
+	for _, s := range []string{"<html", "desc A", `value="owner/repo@v1.0.0"`} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("expected %q in page:\n%s", s, body)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/r/owner/repo@v2.0.0", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 for missing go.mod, got %d", w.Code)
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != errorCacheControl {
+		t.Fatalf("expected Cache-Control %q, got %q", errorCacheControl, cc)
+	}
+}
+
+func TestHandlePastePage(t *testing.T) {
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	srv.cache.Set("apple/a", "desc A")
+	srv.cache.Set("banana/b", "desc B")
+
+	form := url.Values{}
+	form.Set("content", testGoMod)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, req)
+
+	link := "/s/" + pasteHash([]byte(testGoMod))
+	if !strings.Contains(w.Body.String(), fmt.Sprintf(`href=%q`, link)) {
+		t.Fatalf("expected permalink %q in results:\n%s", link, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, link, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d\n%s", w.Code, w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != fixedCacheControl {
+		t.Fatalf("expected Cache-Control %q, got %q", fixedCacheControl, cc)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "desc B") || !strings.Contains(body, "module example.com/test") {
+		t.Fatalf("expected results and content in page:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/s/0123", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown hash, got %d", w.Code)
+	}
+}
+
+func TestHandleRepoAPI(t *testing.T) {
+	srv := setupRawGoMod(t, "HEAD")
+
+	w := httptest.NewRecorder()
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/owner/repo", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d\n%s", w.Code, w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != headCacheControl {
+		t.Fatalf("expected Cache-Control %q, got %q", headCacheControl, cc)
+	}
+
+	var pkgs []PkgInfo
+	if err := json.NewDecoder(w.Body).Decode(&pkgs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("expected 2 pkgs, got %d", len(pkgs))
+	}
+}
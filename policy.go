@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Policy is a set of rules the dependencies must follow.
+type Policy struct {
+	// AllowLicenses, if not empty, are the only allowed licenses.
+	AllowLicenses []string
+	DenyLicenses  []string
+	// DenyVulns denies dependencies with known vulnerabilities.
+	DenyVulns bool
+	// DenyDeprecated denies deprecated modules.
+	DenyDeprecated bool
+	// DenyRetracted denies retracted versions.
+	DenyRetracted bool
+	// DenyWarnings denies dependencies with supply chain warnings.
+	DenyWarnings bool
+}
+
+// Violation is a policy rule broken by a dependency.
+type Violation struct {
+	Pkg    string
+	Rule   string
+	Detail string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s - %s", v.Pkg, v.Rule, v.Detail)
+}
+
+var (
+	checkPolicy bool
+	policy      Policy
+)
+
+// listFlag is a comma separated list flag.
+type listFlag struct{ values *[]string }
+
+func (f listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f listFlag) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f.values = append(*f.values, v)
+		}
+	}
+	return nil
+}
+
+// hasLicense reports whether id is in ids, deprecated SPDX IDs match their
+// replacements.
+func hasLicense(ids []string, id string) bool {
+	id = canonicalLicense(id)
+	for _, i := range ids {
+		if strings.EqualFold(canonicalLicense(i), id) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsLicenses reports whether the policy has license rules.
+func (p Policy) needsLicenses() bool {
+	return len(p.AllowLicenses) > 0 || len(p.DenyLicenses) > 0
+}
+
+// Check returns the violations of the policy in pkgs.
+func (p Policy) Check(pkgs []PkgInfo) []Violation {
+	var violations []Violation
+	for _, pkg := range pkgs {
+		if p.needsLicenses() {
+			violations = append(violations, p.checkLicense(pkg)...)
+		}
+		if p.DenyVulns {
+			for _, v := range pkg.Vulns {
+				violations = append(violations, Violation{pkg.Name, "vuln", fmt.Sprintf("%s %s", v.ID, v.Summary)})
+			}
+		}
+		if p.DenyWarnings {
+			for _, w := range pkg.Warnings {
+				violations = append(violations, Violation{pkg.Name, w.Kind, w.Detail})
+			}
+		}
+		if p.DenyDeprecated && pkg.Deprecated != nil {
+			violations = append(violations, Violation{pkg.Name, "deprecated", pkg.Deprecated.Message})
+		}
+		if p.DenyRetracted && pkg.Retracted != nil {
+			detail := fmt.Sprintf("%s is retracted", pkg.Version)
+			if pkg.Retracted.Rationale != "" {
+				detail += ": " + pkg.Retracted.Rationale
+			}
+			violations = append(violations, Violation{pkg.Name, "retracted", detail})
+		}
+	}
+	return violations
+}
+
+func (p Policy) checkLicense(pkg PkgInfo) []Violation {
+	if pkg.License == nil {
+		return []Violation{{pkg.Name, "license", "unknown license"}}
+	}
+
+	id := pkg.License.ID
+	if hasLicense(p.DenyLicenses, id) {
+		return []Violation{{pkg.Name, "license", fmt.Sprintf("%s is denied", id)}}
+	}
+	if len(p.AllowLicenses) > 0 && !hasLicense(p.AllowLicenses, id) {
+		return []Violation{{pkg.Name, "license", fmt.Sprintf("%s is not allowed", id)}}
+	}
+	return nil
+}
+
+func displayViolations(w io.Writer, violations []Violation) {
+	for _, v := range violations {
+		fmt.Fprintf(w, "policy: %s\n", v)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestPolicyLicenses(t *testing.T) {
+	pkgs := []PkgInfo{
+		{Name: "a", License: &License{ID: "MIT"}},
+		{Name: "b", License: &License{ID: "GPL-3.0-only"}},
+		{Name: "c"},
+		{Name: "d", License: &License{ID: "Apache-2.0"}},
+	}
+
+	// Deprecated IDs match their replacements.
+	p := Policy{
+		AllowLicenses: []string{"mit", "GPL-3.0-only"},
+		DenyLicenses:  []string{"GPL-3.0"},
+	}
+	violations := p.Check(pkgs)
+	if len(violations) != 3 {
+		t.Fatalf("expected 3 violations, got %v", violations)
+	}
+
+	expected := []string{"b", "c", "d"}
+	for i, v := range violations {
+		if v.Pkg != expected[i] || v.Rule != "license" {
+			t.Fatalf("%d: unexpected violation %v", i, v)
+		}
+	}
+
+	if violations := (Policy{}).Check(pkgs); len(violations) != 0 {
+		t.Fatalf("empty policy: unexpected violations %v", violations)
+	}
+}
+
+func TestPolicyDeprecated(t *testing.T) {
+	pkgs := []PkgInfo{
+		{Name: "a", Deprecated: &Deprecation{Message: "use b"}},
+		{Name: "b", Version: "v1.0.0", Retracted: &Retraction{Rationale: "oops"}},
+		{Name: "c"},
+	}
+
+	p := Policy{DenyDeprecated: true, DenyRetracted: true}
+	violations := p.Check(pkgs)
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %v", violations)
+	}
+	if v := violations[0]; v.Pkg != "a" || v.Rule != "deprecated" {
+		t.Fatalf("unexpected violation %v", v)
+	}
+	if v := violations[1]; v.Pkg != "b" || v.Rule != "retracted" || v.Detail != "v1.0.0 is retracted: oops" {
+		t.Fatalf("unexpected violation %v", v)
+	}
+}
+
+func TestPolicyWarnings(t *testing.T) {
+	pkgs := []PkgInfo{
+		{Name: "a", Warnings: []Warning{{"lookalike", "a is 1 edit(s) away from b"}, {"new-repo", "created yesterday"}}},
+		{Name: "b"},
+	}
+
+	if violations := (Policy{}).Check(pkgs); len(violations) != 0 {
+		t.Fatalf("empty policy: unexpected violations %v", violations)
+	}
+
+	violations := Policy{DenyWarnings: true}.Check(pkgs)
+	if len(violations) != 2 || violations[0].Rule != "lookalike" || violations[1].Rule != "new-repo" {
+		t.Fatalf("unexpected violations %v", violations)
+	}
+}
+
+func Test_listFlag(t *testing.T) {
+	var values []string
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(listFlag{&values}, "l", "list")
+	if err := fs.Parse([]string{"-l", "MIT, BSD-3-Clause", "-l", "ISC,"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if len(values) != 3 || values[0] != "MIT" || values[1] != "BSD-3-Clause" || values[2] != "ISC" {
+		t.Fatalf("unexpected values: %q", values)
+	}
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"strings"
 
+	"golang.org/x/mod/module"
 	"golang.org/x/net/html"
 )
 
+var goProxyBase = "https://proxy.golang.org"
+
 func proxyRepo(ctx context.Context, dep string) (string, error) {
 	url := fmt.Sprintf("https://%s?go-get=1", dep)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -92,3 +96,77 @@ func parseProxyHTML(r io.Reader) (string, error) {
 
 	return "", fmt.Errorf("non of %s found in metadata", strings.Join(names, ", "))
 }
+
+// proxyGet returns the body of a module proxy endpoint for path.
+// file is the file name under @v (e.g. "v1.2.3.mod" or "list").
+func proxyGet(ctx context.Context, path, file string) ([]byte, error) {
+	return proxyGetLimit(ctx, path, file, maxModSize)
+}
+
+// proxyGetLimit is proxyGet reading up to limit bytes.
+func proxyGetLimit(ctx context.Context, path, file string, limit int64) ([]byte, error) {
+	escaped, err := module.EscapePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/%s/@v/%s", goProxyBase, escaped, file)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return nil, fmt.Errorf("GET %q - %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %q - %s", url, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// proxyModFile returns the go.mod file of mod from the module proxy.
+func proxyModFile(ctx context.Context, mod module.Version) ([]byte, error) {
+	version, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return proxyGet(ctx, mod.Path, version+".mod")
+}
+
+// proxyLatest returns the latest version of the module from the module proxy.
+func proxyLatest(ctx context.Context, path string) (string, error) {
+	escaped, err := module.EscapePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/%s/@latest", goProxyBase, escaped)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return "", fmt.Errorf("GET %q - %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %q - %s", url, resp.Status)
+	}
+
+	var info struct {
+		Version string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", fmt.Errorf("%q: can't decode JSON - %w", url, err)
+	}
+	return info.Version, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Web server abuse protection options.
+var (
+	rateLimit      = 30.0 // requests per minute per client, 0 disables
+	rateBurst      = 10
+	badgeRateLimit = 60.0 // badge computations per minute per repository, 0 disables
+	trustedProxies []string // CIDRs or IPs allowed to set X-Forwarded-For
+	maxModules     = 300    // per request, 0 is unlimited
+)
+
+var errTooManyModules = errors.New("too many modules")
+
+// bucket is a token bucket, tokens are refilled on use.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a per client token bucket rate limiter.
+type rateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+// newRateLimiter returns a limiter allowing perMinute requests per client,
+// with bursts of up to burst requests.
+func newRateLimiter(perMinute float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    perMinute / 60,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// allow takes a token from the client bucket, if there's none it returns
+// false and the time until the next token.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / l.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// prune removes the buckets which are full again, at most once a minute.
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// parsePrefixes parses CIDRs or single IPs.
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("%q: bad IP - %w", v, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		p, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("%q: bad CIDR - %w", v, err)
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes, nil
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the client address of r. X-Forwarded-For is used only
+// when the request comes from a trusted proxy, the client is the rightmost
+// address which is not a trusted proxy.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(addr, trusted) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		a, err := netip.ParseAddr(hop)
+		if err != nil {
+			// Garbage from the client, stop at the last known hop.
+			break
+		}
+		host = a.Unmap().String()
+		if !isTrusted(a, trusted) {
+			break
+		}
+	}
+	return host
+}
+
+// limit wraps h with per client rate limiting, limited requests get a 429.
+func (s *server) limit(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter == nil {
+			h(w, r)
+			return
+		}
+
+		client := clientIP(r, s.trusted)
+		ok, wait := s.limiter.allow(client, time.Now())
+		if !ok {
+			tooManyRequests(w, wait, "client", client)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// tooManyRequests replies with a 429 and the Retry-After of wait, args are
+// logged.
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, args ...any) {
+	secs := int(math.Ceil(wait.Seconds()))
+	slog.Info("rate limited", append(args, "retry", secs)...)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	http.Error(w, fmt.Sprintf("rate limit exceeded, retry in %d seconds", secs), http.StatusTooManyRequests)
+}
+
+// checkModules returns an error if data has more than maxModules requirements.
+func checkModules(data []byte) error {
+	if maxModules <= 0 {
+		return nil
+	}
+
+	reqs, err := parseRequires(data, inputFormat, includeIndirect)
+	if err != nil {
+		return err
+	}
+	return checkModuleCount(len(reqs))
+}
+
+// checkModuleCount returns an error if n is more than maxModules.
+func checkModuleCount(n int) error {
+	if maxModules > 0 && n > maxModules {
+		return fmt.Errorf("%w: %d, the maximum is %d", errTooManyModules, n, maxModules)
+	}
+	return nil
+}
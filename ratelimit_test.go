@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(60, 2) // 1 per second
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 2 {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("%d: expected burst to be allowed", i)
+		}
+	}
+
+	ok, wait := l.allow("a", now)
+	if ok {
+		t.Fatal("expected limit after burst")
+	}
+	if wait != time.Second {
+		t.Fatalf("expected 1s wait, got %v", wait)
+	}
+
+	if ok, _ := l.allow("b", now); !ok {
+		t.Fatal("expected other client to be allowed")
+	}
+
+	if ok, _ := l.allow("a", now.Add(time.Second)); !ok {
+		t.Fatal("expected refill after 1s")
+	}
+
+	// Full buckets are pruned.
+	l.allow("c", now.Add(time.Hour))
+	if len(l.buckets) != 1 {
+		t.Fatalf("expected 1 bucket after prune, got %d", len(l.buckets))
+	}
+}
+
+var clientIPCases = []struct {
+	remote string
+	xff    string
+	want   string
+}{
+	{"203.0.113.7:1234", "", "203.0.113.7"},
+	{"203.0.113.7:1234", "198.51.100.1", "203.0.113.7"}, // untrusted remote
+	{"10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+	{"10.0.0.1:1234", "1.1.1.1, 198.51.100.1, 10.0.0.2", "198.51.100.1"}, // spoofed first hop
+	{"10.0.0.1:1234", "", "10.0.0.1"},
+	{"10.0.0.1:1234", "junk", "10.0.0.1"},
+	{"[::ffff:10.0.0.1]:1234", "198.51.100.1", "198.51.100.1"},
+}
+
+func Test_clientIP(t *testing.T) {
+	trusted, err := parsePrefixes([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("parsePrefixes: %v", err)
+	}
+
+	for _, tc := range clientIPCases {
+		t.Run(fmt.Sprintf("%s %s", tc.remote, tc.xff), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tc.remote
+			if tc.xff != "" {
+				r.Header.Set("X-Forwarded-For", tc.xff)
+			}
+			if got := clientIP(r, trusted); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_parsePrefixesError(t *testing.T) {
+	if _, err := parsePrefixes([]string{"10.0.0.0/99"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestServerLimit(t *testing.T) {
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	srv.limiter = newRateLimiter(1, 1)
+	h := srv.limit(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodPost, "/api", nil)
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "60" {
+		t.Fatalf("expected Retry-After 60, got %q", ra)
+	}
+}
+
+func TestHandleAPITooManyModules(t *testing.T) {
+	prev := maxModules
+	maxModules = 1
+	defer func() { maxModules = prev }()
+
+	if err := checkModules([]byte(testGoMod)); !errors.Is(err, errTooManyModules) {
+		t.Fatalf("expected errTooManyModules, got %v", err)
+	}
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+
+	form := url.Values{}
+	form.Set("content", testGoMod)
+	req := httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	srv.handleAPI(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// purl returns the package URL of a Go module.
+// e.g. github.com/go-yaml/yaml/v3 v3.0.1 -> pkg:golang/github.com/go-yaml/yaml/v3@v3.0.1
+func purl(path, version string) string {
+	fields := strings.Split(path, "/")
+	for i, f := range fields {
+		fields[i] = url.PathEscape(f)
+	}
+
+	p := "pkg:golang/" + strings.Join(fields, "/")
+	if version != "" {
+		p += "@" + url.PathEscape(version)
+	}
+	return p
+}
+
+// supplier returns the repository owner name and URL of pkg.
+func supplier(pkg PkgInfo) (string, string) {
+	owner, _ := repoInfo(strings.TrimPrefix(pkg.URL, "https://"))
+	if owner == "" {
+		return "", ""
+	}
+	return owner, "https://github.com/" + owner
+}
+
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// CycloneDX 1.5 document, only the fields we use.
+type cdxBOM struct {
+	BOMFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	SerialNumber    string             `json:"serialNumber"`
+	Version         int                `json:"version"`
+	Metadata        cdxMetadata        `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Dependencies    []cdxDependency    `json:"dependencies"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type               string           `json:"type"`
+	BOMRef             string           `json:"bom-ref,omitempty"`
+	Name               string           `json:"name"`
+	Version            string           `json:"version,omitempty"`
+	Description        string           `json:"description,omitempty"`
+	Supplier           *cdxSupplier     `json:"supplier,omitempty"`
+	PURL               string           `json:"purl,omitempty"`
+	Licenses           []cdxLicense     `json:"licenses,omitempty"`
+	ExternalReferences []cdxExternalRef `json:"externalReferences,omitempty"`
+	Properties         []cdxProperty    `json:"properties,omitempty"`
+}
+
+type cdxSupplier struct {
+	Name string   `json:"name"`
+	URL  []string `json:"url,omitempty"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseID `json:"license"`
+}
+
+type cdxLicenseID struct {
+	ID string `json:"id"`
+}
+
+type cdxExternalRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxVulnerability struct {
+	ID             string      `json:"id"`
+	Source         cdxSource   `json:"source"`
+	Description    string      `json:"description,omitempty"`
+	Recommendation string      `json:"recommendation,omitempty"`
+	Affects        []cdxAffect `json:"affects"`
+}
+
+type cdxSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type cdxAffect struct {
+	Ref string `json:"ref"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// cycloneDX returns a CycloneDX SBOM for the dependencies of main.
+func cycloneDX(main string, pkgs []PkgInfo, now time.Time) cdxBOM {
+	root := cdxComponent{
+		Type:   "application",
+		BOMRef: purl(main, ""),
+		Name:   main,
+		PURL:   purl(main, ""),
+	}
+
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: now.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{
+				{Type: "application", Name: "expmod", Version: buildVersion()},
+			}},
+			Component: root,
+		},
+		Components: []cdxComponent{},
+	}
+
+	rootDep := cdxDependency{Ref: root.BOMRef, DependsOn: []string{}}
+	for _, p := range pkgs {
+		c := cdxComponent{
+			Type:        "library",
+			BOMRef:      purl(p.Name, p.Version),
+			Name:        p.Name,
+			Version:     p.Version,
+			Description: p.Desc,
+			PURL:        purl(p.Name, p.Version),
+		}
+		if name, url := supplier(p); name != "" {
+			c.Supplier = &cdxSupplier{Name: name, URL: []string{url}}
+		}
+		if p.License != nil {
+			c.Licenses = []cdxLicense{{License: cdxLicenseID{ID: p.License.ID}}}
+		}
+		if p.URL != "" {
+			c.ExternalReferences = []cdxExternalRef{
+				{Type: "vcs", URL: p.URL},
+				{Type: "website", URL: p.URL},
+			}
+		}
+		for _, v := range p.Vulns {
+			vuln := cdxVulnerability{
+				ID:          v.ID,
+				Source:      cdxSource{Name: "Go Vulnerability Database", URL: v.URL()},
+				Description: v.Summary,
+				Affects:     []cdxAffect{{Ref: c.BOMRef}},
+			}
+			if v.Fixed != "" {
+				vuln.Recommendation = "Upgrade to " + v.Fixed
+			}
+			bom.Vulnerabilities = append(bom.Vulnerabilities, vuln)
+		}
+		for _, w := range p.Warnings {
+			c.Properties = append(c.Properties, cdxProperty{Name: "expmod:warning", Value: w.String()})
+		}
+		if p.Indirect {
+			c.Properties = append(c.Properties, cdxProperty{Name: "cdx:gomod:indirect", Value: "true"})
+		} else {
+			rootDep.DependsOn = append(rootDep.DependsOn, c.BOMRef)
+		}
+		bom.Components = append(bom.Components, c)
+	}
+	bom.Dependencies = []cdxDependency{rootDep}
+
+	return bom
+}
+
+// SPDX 2.3 document, only the fields we use.
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	Supplier         string            `json:"supplier,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Homepage         string            `json:"homepage,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	Description      string            `json:"description,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+	Comment            string `json:"comment,omitempty"`
+}
+
+const spdxNoAssertion = "NOASSERTION"
+
+var spdxIDReplacer = strings.NewReplacer("/", "-", "@", "-", "+", "-", "_", "-", "~", "-")
+
+// spdxID returns a valid SPDX identifier (letters, numbers, . and -).
+func spdxID(parts ...string) string {
+	return "SPDXRef-" + spdxIDReplacer.Replace(strings.Join(parts, "-"))
+}
+
+// spdx returns an SPDX SBOM for the dependencies of main.
+func spdx(main string, pkgs []PkgInfo, now time.Time) spdxDocument {
+	rootID := spdxID("Package", main)
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              main,
+		DocumentNamespace: fmt.Sprintf("https://github.com/tebeka/expmod/spdx/%s-%s", url.PathEscape(main), newUUID()),
+		CreationInfo: spdxCreationInfo{
+			Created:  now.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: expmod-" + buildVersion()},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:           rootID,
+			Name:             main,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			ExternalRefs:     []spdxExternalRef{purlRef(main, "")},
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: rootID,
+		}},
+	}
+
+	for _, p := range pkgs {
+		pkg := spdxPackage{
+			SPDXID:           spdxID("Package", p.Name, p.Version),
+			Name:             p.Name,
+			VersionInfo:      p.Version,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			Description:      p.Desc,
+			ExternalRefs:     []spdxExternalRef{purlRef(p.Name, p.Version)},
+		}
+		if name, _ := supplier(p); name != "" {
+			pkg.Supplier = "Organization: " + name
+		}
+		if p.License != nil {
+			pkg.LicenseDeclared = p.License.ID
+		}
+		if len(p.Warnings) > 0 {
+			warnings := make([]string, len(p.Warnings))
+			for i, w := range p.Warnings {
+				warnings[i] = w.String()
+			}
+			pkg.Comment = "expmod warnings: " + strings.Join(warnings, "; ")
+		}
+		for _, v := range p.Vulns {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+				ReferenceCategory: "SECURITY",
+				ReferenceType:     "advisory",
+				ReferenceLocator:  v.URL(),
+			})
+		}
+		if p.URL != "" {
+			pkg.Homepage = p.URL
+			pkg.DownloadLocation = "git+" + p.URL + ".git"
+		}
+		doc.Packages = append(doc.Packages, pkg)
+
+		rel := spdxRelationship{
+			SPDXElementID:      rootID,
+			RelationshipType:   "DEPENDS_ON",
+			RelatedSPDXElement: pkg.SPDXID,
+		}
+		if p.Indirect {
+			rel.Comment = "indirect"
+		}
+		doc.Relationships = append(doc.Relationships, rel)
+	}
+
+	return doc
+}
+
+func purlRef(path, version string) spdxExternalRef {
+	return spdxExternalRef{
+		ReferenceCategory: "PACKAGE-MANAGER",
+		ReferenceType:     "purl",
+		ReferenceLocator:  purl(path, version),
+	}
+}
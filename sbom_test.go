@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var purlCases = []struct {
+	path    string
+	version string
+	purl    string
+}{
+	{"github.com/go-yaml/yaml/v3", "v3.0.1", "pkg:golang/github.com/go-yaml/yaml/v3@v3.0.1"},
+	{"example.com/main", "", "pkg:golang/example.com/main"},
+	{"github.com/a/b", "v0.0.0-20240101000000-abcdef123456+incompatible", "pkg:golang/github.com/a/b@v0.0.0-20240101000000-abcdef123456+incompatible"},
+}
+
+func Test_purl(t *testing.T) {
+	for _, tc := range purlCases {
+		if p := purl(tc.path, tc.version); p != tc.purl {
+			t.Errorf("%s %s: expected %q, got %q", tc.path, tc.version, tc.purl, p)
+		}
+	}
+}
+
+var sbomPkgs = []PkgInfo{
+	{
+		Name: "github.com/apple/a", Version: "v1.2.3", Desc: "desc A", URL: "https://github.com/apple/a",
+		License:  &License{ID: "Apache-2.0", Confidence: 1, Source: "github"},
+		Vulns:    []Vuln{{ID: "GO-2024-0001", Summary: "bad | thing", Fixed: "v1.2.4"}},
+		Warnings: []Warning{{Kind: "lookalike", Detail: "similar to github.com/apple/b"}},
+	},
+	{Name: "golang.org/x/mod", Version: "v0.34.0", Desc: "mod", URL: "https://github.com/golang/mod", Indirect: true},
+	{Name: "example.com/private", Version: "v0.1.0"},
+	{Name: "github.com/banana/b", Version: "v0.0.0-20240101000000-abcdef123456+incompatible"},
+}
+
+// sbomSchemaDir has the SBOM JSON schemas, "make sbom-schemas" updates them
+// from upstream.
+const sbomSchemaDir = "testdata/sbom"
+
+// validateSchema validates doc against the schema in file (in sbomSchemaDir).
+// The other schemas in the directory are loaded for references.
+func validateSchema(t *testing.T, file string, doc []byte) {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(sbomSchemaDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	ids := make(map[string]string) // file -> $id
+	for _, name := range files {
+		f, err := os.Open(name) // #nosec G304
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, err := jsonschema.UnmarshalJSON(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// Schemas reference each other by their $id URL.
+		id, _ := schema.(map[string]any)["$id"].(string)
+		if id == "" {
+			t.Fatalf("%s: missing $id", name)
+		}
+		if err := c.AddResource(id, schema); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		ids[filepath.Base(name)] = id
+	}
+
+	id, ok := ids[file]
+	if !ok {
+		t.Fatalf("%s: missing schema in %s", file, sbomSchemaDir)
+	}
+	sch, err := c.Compile(id)
+	if err != nil {
+		t.Fatalf("%s: compile: %v", file, err)
+	}
+
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if err := sch.Validate(v); err != nil {
+		t.Fatalf("%s: %v\n%s", file, err, doc)
+	}
+}
+
+var uuidRE = regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func Test_cycloneDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writePkgs(&buf, "example.com/test", sbomPkgs, formatCycloneDX); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	validateSchema(t, "bom-1.5.schema.json", buf.Bytes())
+
+	var bom cdxBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// Not checked by the schema
+	if !uuidRE.MatchString(bom.SerialNumber) {
+		t.Fatalf("bad serial number: %q", bom.SerialNumber)
+	}
+
+	refs := map[string]bool{bom.Metadata.Component.BOMRef: true}
+	for _, c := range bom.Components {
+		if !strings.HasPrefix(c.PURL, "pkg:golang/") {
+			t.Fatalf("bad purl: %+v", c)
+		}
+		if refs[c.BOMRef] {
+			t.Fatalf("duplicate bom-ref %q", c.BOMRef)
+		}
+		refs[c.BOMRef] = true
+	}
+
+	apple := bom.Components[0]
+	if apple.Supplier == nil || apple.Supplier.Name != "apple" || apple.Supplier.URL[0] != "https://github.com/apple" {
+		t.Fatalf("apple: bad supplier %+v", apple.Supplier)
+	}
+
+	if len(bom.Dependencies) != 1 {
+		t.Fatalf("expected root dependencies, got %+v", bom.Dependencies)
+	}
+	root := bom.Dependencies[0]
+	// indirect dependency is not a direct child of the root
+	if len(root.DependsOn) != 3 || root.DependsOn[0] != apple.BOMRef {
+		t.Fatalf("bad root dependencies: %+v", root)
+	}
+	for _, ref := range root.DependsOn {
+		if !refs[ref] {
+			t.Fatalf("unknown ref %q", ref)
+		}
+	}
+}
+
+var spdxIDRE = regexp.MustCompile(`^SPDXRef-[a-zA-Z0-9.-]+$`)
+
+func Test_spdx(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writePkgs(&buf, "example.com/test", sbomPkgs, formatSPDX); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	validateSchema(t, "spdx-schema.json", buf.Bytes())
+
+	var doc spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	// Not checked by the schema
+	ids := map[string]bool{doc.SPDXID: true}
+	for _, p := range doc.Packages {
+		if !spdxIDRE.MatchString(p.SPDXID) {
+			t.Fatalf("bad SPDXID %q", p.SPDXID)
+		}
+		if ids[p.SPDXID] {
+			t.Fatalf("duplicate SPDXID %q", p.SPDXID)
+		}
+		ids[p.SPDXID] = true
+	}
+
+	if len(doc.Packages) != 5 || len(doc.Relationships) != 5 {
+		t.Fatalf("expected 5 packages & relationships, got %d, %d", len(doc.Packages), len(doc.Relationships))
+	}
+	for _, r := range doc.Relationships {
+		if !ids[r.SPDXElementID] || !ids[r.RelatedSPDXElement] {
+			t.Fatalf("relationship with unknown element: %+v", r)
+		}
+	}
+
+	mod := doc.Packages[2]
+	if mod.Supplier != "Organization: golang" || mod.Homepage != "https://github.com/golang/mod" {
+		t.Fatalf("mod: bad supplier info %+v", mod)
+	}
+	if doc.Relationships[2].Comment != "indirect" {
+		t.Fatalf("expected indirect relationship, got %+v", doc.Relationships[2])
+	}
+}
+
+func Test_writePkgsBadFormat(t *testing.T) {
+	if err := writePkgs(&bytes.Buffer{}, "main", sbomPkgs, "xml"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ModuleReport is the report for a single go.mod found in a scan.
+type ModuleReport struct {
+	Path string // module path
+	File string // go.mod file name
+	Pkgs []PkgInfo
+}
+
+// ScanReport is the report for a directory tree.
+type ScanReport struct {
+	Modules []ModuleReport
+	// Deps is the combined, deduplicated, dependency inventory.
+	Deps []WorkspaceDep
+}
+
+// Conflicts returns the dependencies required at different versions.
+func (r ScanReport) Conflicts() []WorkspaceDep {
+	var deps []WorkspaceDep
+	for _, d := range r.Deps {
+		if d.Skewed() {
+			deps = append(deps, d)
+		}
+	}
+	return deps
+}
+
+// scanRoot converts a "./..." style pattern to a directory.
+func scanRoot(pattern string) string {
+	root := strings.TrimSuffix(pattern, "...")
+	root = strings.TrimSuffix(root, "/")
+	if root == "" || root == "." {
+		return "."
+	}
+	return root
+}
+
+// skipDir reports whether the scan should skip a directory, the go tool
+// ignores the same ones.
+func skipDir(name string) bool {
+	if name == "vendor" || name == "testdata" {
+		return true
+	}
+	return len(name) > 1 && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_"))
+}
+
+// findModFiles returns the go.mod files under root.
+func findModFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.Name() == "go.mod" {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// scanInfo describes every module under root. When ctx is done, the
+// modules described so far are returned with ctx.Err().
+func scanInfo(ctx context.Context, root string, cache repoCache) (ScanReport, error) {
+	files, err := findModFiles(root)
+	if err != nil {
+		return ScanReport{}, err
+	}
+
+	if len(files) == 0 {
+		return ScanReport{}, fmt.Errorf("%q: no go.mod files found", root)
+	}
+
+	var (
+		report ScanReport
+		mods   []*modfile.File
+	)
+	for _, fileName := range files {
+		data, err := os.ReadFile(fileName) // #nosec G304
+		if err != nil {
+			return ScanReport{}, err
+		}
+
+		f, err := modfile.Parse(fileName, data, nil)
+		if err != nil {
+			return ScanReport{}, err
+		}
+		if f.Module == nil {
+			return ScanReport{}, fmt.Errorf("%q: missing module directive", fileName)
+		}
+		mods = append(mods, f)
+
+		pkgs, err := pkgsInfo(ctx, bytes.NewReader(data), cache)
+		if err != nil && ctx.Err() == nil {
+			return ScanReport{}, fmt.Errorf("%q: %w", fileName, err)
+		}
+
+		report.Modules = append(report.Modules, ModuleReport{
+			Path: f.Module.Mod.Path,
+			File: fileName,
+			Pkgs: pkgs,
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	reqs := collectRequires(mods, nil, false)
+	report.Deps, err = sharedDepsInfo(ctx, reqs, cache)
+	if err != nil && ctx.Err() == nil {
+		return ScanReport{}, err
+	}
+	return report, err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func Test_findModFiles(t *testing.T) {
+	files, err := findModFiles("testdata/scan")
+	if err != nil {
+		t.Fatalf("findModFiles: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join("testdata", "scan", "go.mod"),
+		filepath.Join("testdata", "scan", "svc", "go.mod"),
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, files)
+		}
+	}
+}
+
+func Test_scanRoot(t *testing.T) {
+	cases := map[string]string{
+		"./...":     ".",
+		"...":       ".",
+		".":         ".",
+		"src/...":   "src",
+		"./src/...": "./src",
+		"src":       "src",
+	}
+	for pattern, expected := range cases {
+		if root := scanRoot(pattern); root != expected {
+			t.Errorf("%q: expected %q, got %q", pattern, expected, root)
+		}
+	}
+}
+
+func Test_scanInfo(t *testing.T) {
+	cache := mapCache{m: map[string]string{
+		"apple/a":  "desc A",
+		"banana/b": "desc B",
+	}}
+
+	report, err := scanInfo(t.Context(), "testdata/scan", cache)
+	if err != nil {
+		t.Fatalf("scanInfo: %v", err)
+	}
+
+	if len(report.Modules) != 2 {
+		t.Fatalf("expected 2 modules, got %d", len(report.Modules))
+	}
+	svc := report.Modules[1]
+	if svc.Path != "example.com/root/svc" || len(svc.Pkgs) != 2 {
+		t.Fatalf("svc: unexpected %+v", svc)
+	}
+
+	if len(report.Deps) != 2 {
+		t.Fatalf("expected 2 deps, got %d", len(report.Deps))
+	}
+
+	conflicts := report.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Name != "github.com/apple/a" {
+		t.Fatalf("unexpected conflicts: %+v", conflicts)
+	}
+	if conflicts[0].Version != "v1.3.0" {
+		t.Fatalf("expected max version, got %q", conflicts[0].Version)
+	}
+}
+
+func Test_scanInfoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cache := cancelCache{
+		mapCache: mapCache{m: map[string]string{"apple/a": "desc A", "banana/b": "desc B"}},
+		key:      "banana/b",
+		cancel:   cancel,
+	}
+
+	report, err := scanInfo(ctx, "testdata/scan", cache)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(report.Modules) == 0 {
+		t.Fatal("expected partial report")
+	}
+}
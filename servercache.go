@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Web server cache options.
+var (
+	serverCacheSize = 512
+	serverCacheTTL  = 24 * time.Hour
+	serverCacheFile string
+	warmupFile      string
+)
+
+const (
+	// cacheFlushInterval is how often a dirty server cache is written to disk.
+	cacheFlushInterval = time.Minute
+	// sizedCacheEntries caps the number of entries in a sizedCache.
+	sizedCacheEntries = 4096
+)
+
+// Total size of cached go.mod files and pastes.
+var (
+	modCacheBytes   = 32 << 20
+	pasteCacheBytes = 16 << 20
+)
+
+// cacheEntry is a cached value and its expiry time.
+type cacheEntry struct {
+	Key     string
+	Value   string
+	Expires time.Time
+}
+
+// sizedCache is an LRU cache limited by the total size of its values.
+type sizedCache struct {
+	c     *lru.Cache[string, cacheEntry]
+	bytes atomic.Int64
+	max   int64
+}
+
+func newSizedCache(maxBytes int) (*sizedCache, error) {
+	sc := &sizedCache{max: int64(maxBytes)}
+	var err error
+	sc.c, err = lru.NewWithEvict(sizedCacheEntries, func(_ string, e cacheEntry) {
+		sc.bytes.Add(-int64(len(e.Value)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// add adds e, evicting the least recently used entries until they fit in max.
+// Values larger than max are not cached.
+func (c *sizedCache) add(e cacheEntry) {
+	size := int64(len(e.Value))
+	if size > c.max {
+		return
+	}
+
+	c.c.Remove(e.Key)
+	c.bytes.Add(size)
+	c.c.Add(e.Key, e)
+	for c.bytes.Load() > c.max {
+		if _, _, ok := c.c.RemoveOldest(); !ok {
+			break
+		}
+	}
+}
+
+// serverCache is a size limited LRU cache with expiring entries, which can
+// be persisted to a gob file so it survives restarts.
+// go.mod files ("mod:" keys) and pastes ("paste:" keys) are large, they are
+// kept in separate LRUs limited by total size. go.mod files are immutable and
+// kept in memory only.
+type serverCache struct {
+	c      *lru.Cache[string, cacheEntry]
+	mods   *sizedCache
+	pastes *sizedCache
+	ttl    time.Duration
+
+	mu    sync.Mutex // guards file writes and dirty
+	file  string     // empty for memory only
+	dirty bool
+}
+
+func newServerCache(size int, ttl time.Duration) (*serverCache, error) {
+	c, err := lru.New[string, cacheEntry](size)
+	if err != nil {
+		return nil, err
+	}
+	mods, err := newSizedCache(modCacheBytes)
+	if err != nil {
+		return nil, err
+	}
+	pastes, err := newSizedCache(pasteCacheBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &serverCache{c: c, mods: mods, pastes: pastes, ttl: ttl}, nil
+}
+
+// sized returns the sizedCache holding key, nil for the main LRU.
+func (c *serverCache) sized(key string) *sizedCache {
+	switch {
+	case strings.HasPrefix(key, "mod:"):
+		return c.mods
+	case strings.HasPrefix(key, "paste:"):
+		return c.pastes
+	}
+	return nil
+}
+
+func (c *serverCache) Get(key string) (string, bool) {
+	l := c.c
+	if sc := c.sized(key); sc != nil {
+		l = sc.c
+	}
+	e, ok := l.Get(key)
+	if ok && time.Now().After(e.Expires) {
+		l.Remove(key)
+		ok = false
+	}
+
+	if !ok {
+		cacheRequests.Inc("miss")
+		return "", false
+	}
+	cacheRequests.Inc("hit")
+	return e.Value, true
+}
+
+func (c *serverCache) Set(key, value string) {
+	c.add(cacheEntry{Key: key, Value: value, Expires: time.Now().Add(c.ttl)})
+	if strings.HasPrefix(key, "mod:") {
+		return
+	}
+
+	c.mu.Lock()
+	c.dirty = true
+	c.mu.Unlock()
+}
+
+// add adds e to the LRU holding its key.
+func (c *serverCache) add(e cacheEntry) {
+	if sc := c.sized(e.Key); sc != nil {
+		sc.add(e)
+		return
+	}
+	c.c.Add(e.Key, e)
+}
+
+// Len returns the number of persisted entries, including expired ones not
+// evicted yet.
+func (c *serverCache) Len() int {
+	return c.c.Len() + c.pastes.c.Len()
+}
+
+// load reads the entries in file and persists the cache there from now on.
+// A missing file is not an error.
+func (c *serverCache) load(file string) error {
+	c.mu.Lock()
+	c.file = file
+	c.mu.Unlock()
+
+	f, err := os.Open(file) // #nosec G304
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	return c.read(f)
+}
+
+// read adds the entries from r, oldest first, skipping expired ones.
+func (c *serverCache) read(r io.Reader) error {
+	var entries []cacheEntry
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return fmt.Errorf("can't decode cache - %w", err)
+	}
+
+	now := time.Now()
+	for _, e := range entries {
+		if now.Before(e.Expires) {
+			c.add(e)
+		}
+	}
+	return nil
+}
+
+// write writes the entries and pastes to w, oldest first.
+func (c *serverCache) write(w io.Writer) error {
+	entries := append(c.c.Values(), c.pastes.c.Values()...)
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// save writes the cache to its file if it changed since the last save.
+func (c *serverCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == "" || !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.file), 0750); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename, a crash won't leave a partial cache.
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), filepath.Base(c.file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //#nosec G104
+
+	if err := c.write(tmp); err != nil {
+		tmp.Close() //#nosec G104
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.file); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// flush saves the cache every interval until ctx is done.
+func (c *serverCache) flush(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.save(); err != nil {
+				slog.Warn("can't save cache", "file", c.file, "error", err)
+			}
+		}
+	}
+}
+
+// warmup describes the modules in fileName so they are cached before the
+// first request.
+func warmup(ctx context.Context, fileName string, cache repoCache) error {
+	f, err := os.Open(fileName) // #nosec G304
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	paths, err := readModuleList(f)
+	if err != nil {
+		return fmt.Errorf("%q: %w", fileName, err)
+	}
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		depInfo(ctx, path, "", cache)
+	}
+	slog.Info("cache warm", "modules", len(paths))
+	return nil
+}
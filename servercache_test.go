@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerCacheTTL(t *testing.T) {
+	c, err := newServerCache(8, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+
+	c.Set("a", "1")
+	if v, ok := c.Get("a"); !ok || v != "1" {
+		t.Fatalf("expected 1, got %q (%v)", v, ok)
+	}
+
+	c.c.Add("b", cacheEntry{Key: "b", Value: "2", Expires: time.Now().Add(-time.Second)})
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected expired entry to be missing")
+	}
+	if c.Len() != 1 {
+		t.Fatalf("expected expired entry to be removed, got %d entries", c.Len())
+	}
+}
+
+func TestServerCacheModFiles(t *testing.T) {
+	old := modCacheBytes
+	modCacheBytes = 25
+	defer func() { modCacheBytes = old }()
+
+	c, err := newServerCache(8, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+
+	c.Set("mod:a@v1", "0123456789")
+	c.Set("mod:b@v1", "0123456789")
+	c.Set("mod:c@v1", "0123456789")
+	if _, ok := c.Get("mod:a@v1"); ok {
+		t.Fatal("expected oldest go.mod to be evicted")
+	}
+	for _, key := range []string{"mod:b@v1", "mod:c@v1"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%q: missing", key)
+		}
+	}
+
+	c.Set("mod:big@v1", strings.Repeat("x", 26))
+	if _, ok := c.Get("mod:big@v1"); ok {
+		t.Fatal("expected go.mod larger than the cache not to be cached")
+	}
+	if n := c.mods.bytes.Load(); n != 20 {
+		t.Fatalf("expected 20 bytes of go.mod files, got %d", n)
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expected go.mod files out of the persisted cache, got %d entries", c.Len())
+	}
+}
+
+func TestServerCachePastes(t *testing.T) {
+	old := pasteCacheBytes
+	pasteCacheBytes = 25
+	defer func() { pasteCacheBytes = old }()
+
+	c, err := newServerCache(2, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+
+	c.Set("a", "1")
+	for _, key := range []string{"paste:1", "paste:2", "paste:3"} {
+		c.Set(key, "0123456789")
+	}
+	if _, ok := c.Get("paste:1"); ok {
+		t.Fatal("expected oldest paste to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected pastes not to evict entries")
+	}
+
+	var buf bytes.Buffer
+	if err := c.write(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	loaded, err := newServerCache(2, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+	if err := loaded.read(&buf); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for _, key := range []string{"a", "paste:2", "paste:3"} {
+		if _, ok := loaded.Get(key); !ok {
+			t.Fatalf("%q: not persisted", key)
+		}
+	}
+	if n := loaded.pastes.bytes.Load(); n != 20 {
+		t.Fatalf("expected 20 bytes of pastes, got %d", n)
+	}
+}
+
+func TestServerCacheSize(t *testing.T) {
+	c, err := newServerCache(2, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+
+	c.Set("a", "1")
+	c.Set("b", "2")
+	c.Get("a")
+	c.Set("c", "3")
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%q: missing", key)
+		}
+	}
+}
+
+func TestServerCachePersist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "server", "cache.gob")
+
+	c, err := newServerCache(8, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+	if err := c.load(file); err != nil {
+		t.Fatalf("load missing: %v", err)
+	}
+	c.Set("a", "1")
+	c.c.Add("b", cacheEntry{Key: "b", Value: "2", Expires: time.Now().Add(-time.Second)})
+	if err := c.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// Clean cache isn't written.
+	if err := os.Remove(file); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := c.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected no write, got %v", err)
+	}
+
+	c.Set("c", "3")
+	if err := c.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := newServerCache(8, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+	if err := loaded.load(file); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", loaded.Len())
+	}
+	if v, ok := loaded.Get("c"); !ok || v != "3" {
+		t.Fatalf("expected 3, got %q (%v)", v, ok)
+	}
+	if _, ok := loaded.Get("b"); ok {
+		t.Fatal("expected expired entry to be skipped")
+	}
+}
+
+func Test_warmup(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/pkg/errors" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `{"description":"Simple error handling primitives"}`)
+	})
+	defer restore()
+
+	file := filepath.Join(t.TempDir(), "warmup.txt")
+	if err := os.WriteFile(file, []byte("# popular\ngithub.com/pkg/errors\n"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	c, err := newServerCache(8, time.Hour)
+	if err != nil {
+		t.Fatalf("newServerCache: %v", err)
+	}
+	if err := warmup(t.Context(), file, c); err != nil {
+		t.Fatalf("warmup: %v", err)
+	}
+
+	if desc, ok := c.Get("pkg/errors"); !ok || desc != "Simple error handling primitives" {
+		t.Fatalf("expected cached description, got %q (%v)", desc, ok)
+	}
+}
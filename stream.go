@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jobTTL is how long a submitted go.mod waits for its event stream.
+const jobTTL = time.Minute
+
+// job is a go.mod waiting to be streamed.
+type job struct {
+	data    []byte
+	graph   bool
+	created time.Time
+}
+
+// jobs are the pending streaming jobs, the form is posted first and then the
+// browser connects to the event stream.
+type jobs struct {
+	mu sync.Mutex
+	m  map[string]job
+}
+
+func newJobs() *jobs {
+	return &jobs{m: make(map[string]job)}
+}
+
+// add adds a job and returns its ID, expired jobs are removed.
+func (j *jobs) add(data []byte, graph bool) string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range j.m {
+		if now.Sub(v.created) > jobTTL {
+			delete(j.m, k)
+		}
+	}
+	j.m[id] = job{data: data, graph: graph, created: now}
+	return id
+}
+
+// take removes and returns the job.
+func (j *jobs) take(id string) (job, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jb, ok := j.m[id]
+	delete(j.m, id)
+	if ok && time.Since(jb.created) > jobTTL {
+		return job{}, false
+	}
+	return jb, ok
+}
+
+// stream is the data for streamTmpl.
+type stream struct {
+	ID        string
+	Total     int
+	Weighted  bool
+	HasHealth bool
+	Permalink string
+}
+
+// handleStream registers the go.mod and returns a table which is filled from
+// the event stream.
+func (s *server) handleStream(w http.ResponseWriter, r *http.Request) {
+	data, err := s.modFromRequest(w, r)
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+
+	reqs, err := parseRequires(data, inputFormat, includeIndirect)
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+
+	graph := r.FormValue("graph") != ""
+	st := stream{
+		ID:        s.jobs.add(data, graph),
+		Total:     len(reqs),
+		Weighted:  graph,
+		HasHealth: checkHealth,
+		Permalink: permalink(r),
+	}
+	if err := streamTmpl.Execute(w, st); err != nil {
+		slog.Error("render stream", "error", err)
+	}
+}
+
+// writeSSE writes a server-sent event, data may span several lines.
+func writeSSE(w io.Writer, event, data string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// handleEvents streams the rows of a job as they are resolved.
+// Events are "progress" (n of m resolved), "row" (table row) and "done"
+// (graph or error).
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// Streams of large go.mod files outlive the server write timeout.
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("can't clear write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	jb, ok := s.jobs.take(r.PathValue("id"))
+	if !ok {
+		var buf strings.Builder
+		writeHTMLError(&buf, fmt.Errorf("unknown or expired request, please submit again"))
+		_ = writeSSE(w, "done", buf.String())
+		return
+	}
+
+	send := func(event, data string) error {
+		if err := writeSSE(w, event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Rows show the weights, the graph is built before them.
+	var (
+		g        *depGraph
+		graphErr error
+		weights  map[string]Weight
+	)
+	if jb.graph {
+		if err := send("progress", "building graph"); err != nil {
+			return
+		}
+		g, graphErr = s.graph(r.Context(), jb.data)
+		if graphErr == nil {
+			weights = depWeights(g)
+		}
+	}
+
+	var pkgs []PkgInfo
+	err := walkPkgsInfo(r.Context(), bytes.NewReader(jb.data), s.cache, func(p *PkgInfo, n, total int) error {
+		if p != nil {
+			if w, ok := weights[p.Name]; ok {
+				p.Weight = &w
+			}
+			pkgs = append(pkgs, *p)
+
+			var buf strings.Builder
+			if err := streamTmpl.ExecuteTemplate(&buf, "row", row{PkgInfo: *p, Weighted: jb.graph, HasHealth: checkHealth}); err != nil {
+				return err
+			}
+			if err := send("row", buf.String()); err != nil {
+				return err
+			}
+		}
+		return send("progress", fmt.Sprintf("%d of %d resolved", n, total))
+	})
+
+	if r.Context().Err() != nil {
+		slog.Info("client disconnected", "error", err)
+		return
+	}
+
+	var buf strings.Builder
+	switch {
+	case err != nil:
+		writeHTMLError(&buf, err)
+	case jb.graph:
+		err := graphErr
+		if err == nil {
+			buf.WriteString(`<pre class="mermaid">`)
+			var graph strings.Builder
+			if err = writeMermaid(&graph, g); err == nil {
+				template.HTMLEscape(&buf, []byte(graph.String()))
+			}
+			buf.WriteString("</pre>")
+		}
+		if err != nil {
+			slog.Warn("build graph", "error", err)
+		}
+	}
+	if len(pkgs) == 0 && err == nil {
+		buf.WriteString("<p>No direct dependencies found.</p>")
+	}
+	if err := send("done", buf.String()); err != nil {
+		slog.Warn("send done", "error", err)
+	}
+}
+
+// handleAPIStream writes the dependencies as newline delimited JSON, one
+// line per dependency as soon as it is resolved.
+func (s *server) handleAPIStream(w http.ResponseWriter, r *http.Request) {
+	data, err := s.modFromRequest(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), requestErrorStatus(err))
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("can't clear write deadline", "error", err)
+	}
+	w.Header().Set("Content-Type", ndjsonType)
+
+	enc := json.NewEncoder(w)
+	err = walkPkgsInfo(r.Context(), bytes.NewReader(data), s.cache, func(p *PkgInfo, n, total int) error {
+		if p == nil {
+			return nil
+		}
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err != nil && r.Context().Err() == nil {
+		// Headers are sent, report the error as the last line.
+		_ = enc.Encode(map[string]string{"error": err.Error()})
+	}
+}
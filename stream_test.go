@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newTestStreamServer(t *testing.T) (*server, *httptest.Server) {
+	t.Helper()
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	srv.cache.Set("apple/a", "desc A")
+	srv.cache.Set("banana/b", "desc B")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api", srv.handleAPI)
+	mux.HandleFunc("POST /stream", srv.handleStream)
+	mux.HandleFunc("GET /events/{id}", srv.handleEvents)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+var sseConnectRE = regexp.MustCompile(`sse-connect="(/events/[0-9a-f]+)"`)
+
+func TestHandleStream(t *testing.T) {
+	_, ts := newTestStreamServer(t)
+
+	resp, err := http.PostForm(ts.URL+"/stream", url.Values{"content": {testGoMod}})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	var body strings.Builder
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		body.WriteString(s.Text() + "\n")
+	}
+	resp.Body.Close()
+
+	if !strings.Contains(body.String(), "0 of 2 resolved") {
+		t.Fatalf("missing progress:\n%s", body.String())
+	}
+	m := sseConnectRE.FindStringSubmatch(body.String())
+	if m == nil {
+		t.Fatalf("missing sse-connect:\n%s", body.String())
+	}
+
+	resp, err = http.Get(ts.URL + m[1])
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("bad content type: %q", ct)
+	}
+
+	var events []string
+	rows := 0
+	s = bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		line := s.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+		}
+		if strings.HasPrefix(line, "data: <tr>") {
+			rows++
+		}
+	}
+
+	expected := []string{"row", "progress", "row", "progress", "done"}
+	if strings.Join(events, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	if rows != 2 {
+		t.Fatalf("expected 2 rows, got %d", rows)
+	}
+
+	// A job is streamed once.
+	resp, err = http.Get(ts.URL + m[1])
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	defer resp.Body.Close()
+	data := new(strings.Builder)
+	s = bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		data.WriteString(s.Text())
+	}
+	if !strings.Contains(data.String(), "expired") {
+		t.Fatalf("expected expired job, got %q", data.String())
+	}
+}
+
+func TestHandleStreamGraph(t *testing.T) {
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod":  "module github.com/apple/a\n\nrequire github.com/date/d v0.2.0\n",
+		"/github.com/banana/b/@v/v1.0.0.mod": "module github.com/banana/b\n",
+		"/github.com/date/d/@v/v0.2.0.mod":   "module github.com/date/d\n",
+	})
+	srv, ts := newTestStreamServer(t)
+	srv.cache.Set("date/d", "desc D")
+
+	resp, err := http.PostForm(ts.URL+"/stream", url.Values{"content": {testGoMod}, "graph": {"1"}})
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(body), "<th>Deps</th>") {
+		t.Fatalf("missing weight column:\n%s", body)
+	}
+	m := sseConnectRE.FindStringSubmatch(string(body))
+	if m == nil {
+		t.Fatalf("missing sse-connect:\n%s", body)
+	}
+
+	resp, err = http.Get(ts.URL + m[1])
+	if err != nil {
+		t.Fatalf("events: %v", err)
+	}
+	defer resp.Body.Close()
+	events, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	for _, s := range []string{"<td>1 (1 unique)</td>", "<td>0 (0 unique)</td>", "mermaid"} {
+		if !strings.Contains(string(events), s) {
+			t.Fatalf("expected %q in events:\n%s", s, events)
+		}
+	}
+}
+
+func TestServerGraphMaxModules(t *testing.T) {
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod":  "module github.com/apple/a\n\nrequire github.com/date/d v0.2.0\n",
+		"/github.com/banana/b/@v/v1.0.0.mod": "module github.com/banana/b\n",
+		"/github.com/date/d/@v/v0.2.0.mod":   "module github.com/date/d\n",
+	})
+	prev := maxModules
+	maxModules = 1
+	defer func() { maxModules = prev }()
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	// Descriptions would need GitHub, which is not set up.
+	g, err := srv.graph(t.Context(), []byte(testGoMod))
+	if err != nil {
+		t.Fatalf("graph: %v", err)
+	}
+	if !g.Truncated {
+		t.Fatal("expected graph truncated at maxModules")
+	}
+	for path, node := range g.Nodes {
+		if node.Desc != "" {
+			t.Fatalf("%s: expected no description, got %q", path, node.Desc)
+		}
+	}
+}
+
+func TestHandleAPIStream(t *testing.T) {
+	_, ts := newTestStreamServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/api", strings.NewReader(url.Values{"content": {testGoMod}}.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", ndjsonType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != ndjsonType {
+		t.Fatalf("bad content type: %q", ct)
+	}
+
+	var names []string
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var p PkgInfo
+		if err := dec.Decode(&p); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		names = append(names, p.Name)
+	}
+
+	expected := "github.com/apple/a,github.com/banana/b"
+	if strings.Join(names, ",") != expected {
+		t.Fatalf("expected %s, got %v", expected, names)
+	}
+}
+
+func Test_walkPkgsInfoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cache := mapCache{m: map[string]string{"apple/a": "desc A", "banana/b": "desc B"}}
+
+	n := 0
+	err := walkPkgsInfo(ctx, strings.NewReader(testGoMod), cache, func(p *PkgInfo, done, total int) error {
+		n++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 call, got %d", n)
+	}
+}
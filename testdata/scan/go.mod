@@ -0,0 +1,5 @@
+module example.com/root
+
+go 1.22
+
+require github.com/apple/a v1.2.3
@@ -0,0 +1,8 @@
+module example.com/root/svc
+
+go 1.22
+
+require (
+	github.com/apple/a v1.3.0
+	github.com/banana/b v1.0.0
+)
@@ -0,0 +1,3 @@
+module example.com/skip
+
+require github.com/cherry/c v0.1.0
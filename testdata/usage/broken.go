@@ -0,0 +1,3 @@
+package usage
+
+import "github.com/banana/b
@@ -0,0 +1,7 @@
+package main
+
+import "github.com/apple/a/util"
+
+func main() {
+	util.Run()
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/apple/a"
+)
+
+func TestName(t *testing.T) {
+	t.Log(a.Name)
+}
@@ -0,0 +1,11 @@
+module example.com/usage
+
+go 1.22
+
+require (
+	github.com/apple/a v1.2.3
+	github.com/apple/a/sub v0.1.0
+	github.com/banana/b v1.0.0
+	github.com/cherry/c v0.2.0 // indirect
+	github.com/date/d v0.3.0
+)
@@ -0,0 +1,3 @@
+package nested
+
+import "github.com/banana/b"
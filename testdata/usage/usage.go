@@ -0,0 +1,12 @@
+package usage
+
+import (
+	"fmt"
+
+	"github.com/apple/a"
+	"github.com/apple/a/sub/x"
+)
+
+func Hello() {
+	fmt.Println(a.Name, x.Name)
+}
@@ -0,0 +1,10 @@
+module example.com/a
+
+go 1.22
+
+require (
+	example.com/b v0.0.0
+	github.com/apple/a v1.2.3
+	github.com/banana/b v1.0.0
+	github.com/cherry/c v0.1.0 // indirect
+)
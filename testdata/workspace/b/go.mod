@@ -0,0 +1,10 @@
+module example.com/b
+
+go 1.22
+
+require (
+	github.com/apple/a v1.3.0
+	github.com/banana/b v1.0.1
+)
+
+replace github.com/apple/a v1.3.0 => github.com/apple/a v1.3.1
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+//go:embed popular.txt
+var popularModules string
+
+// Warning is a supply chain warning about a dependency.
+type Warning struct {
+	Kind   string // "lookalike", "homoglyph", "swapped" or "new-repo"
+	Detail string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Kind, w.Detail)
+}
+
+// newRepoAge is the age under which a dependency repository is suspicious.
+const newRepoAge = 90 * 24 * time.Hour
+
+var (
+	checkTyposquat bool
+	allowlistFile  string
+	squats         *squatChecker // nil if not checking
+)
+
+// readModuleList returns the module paths in r, one per line.
+// Empty lines and lines starting with # are ignored.
+func readModuleList(r io.Reader) ([]string, error) {
+	var mods []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		mods = append(mods, line)
+	}
+	return mods, s.Err()
+}
+
+// squatChecker finds look-alikes of known module paths.
+type squatChecker struct {
+	known []string
+}
+
+// newSquatChecker returns a checker for the bundled popular modules and the
+// modules in the allowlist file (may be "").
+func newSquatChecker(allowlist string) (*squatChecker, error) {
+	known, err := readModuleList(strings.NewReader(popularModules))
+	if err != nil {
+		return nil, err
+	}
+
+	if allowlist != "" {
+		file, err := os.Open(allowlist) // #nosec G304
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		allowed, err := readModuleList(file)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", allowlist, err)
+		}
+		known = append(known, allowed...)
+	}
+
+	for i, k := range known {
+		known[i] = moduleRoot(k)
+	}
+	return &squatChecker{known: known}, nil
+}
+
+// repoHosts are hosts where the module root is host/owner/repo.
+var repoHosts = map[string]bool{
+	"github.com":    true,
+	"gitlab.com":    true,
+	"bitbucket.org": true,
+}
+
+// moduleRoot returns the path of the repository of a module, without major
+// version suffix.
+// e.g. github.com/aws/aws-sdk-go-v2/service/s3 -> github.com/aws/aws-sdk-go-v2
+func moduleRoot(path string) string {
+	prefix, _, ok := module.SplitPathVersion(path)
+	if ok {
+		path = prefix
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	fields := strings.Split(path, "/")
+	if repoHosts[fields[0]] && len(fields) > 3 {
+		return strings.Join(fields[:3], "/")
+	}
+	return path
+}
+
+// homoglyphs are look-alike characters and sequences, replaced in order.
+var homoglyphs = strings.NewReplacer(
+	// Cyrillic and Greek letters
+	"а", "a", "е", "e", "о", "o", "р", "p", "с", "c", "у", "y", "х", "x",
+	"і", "i", "ј", "j", "ѕ", "s", "ԁ", "d", "ο", "o", "α", "a", "ν", "v",
+	// ASCII
+	"rn", "m", "vv", "w", "cl", "d", "0", "o", "1", "l", "I", "l",
+)
+
+// skeleton returns path with look-alike characters in the owner and
+// repository folded, the host is kept as is.
+// e.g. github.com/g00gle/uuid -> github.com/google/uuid
+func skeleton(path string) string {
+	host, rest, ok := strings.Cut(path, "/")
+	if !ok {
+		return strings.ToLower(path)
+	}
+	return strings.ToLower(host) + "/" + strings.ToLower(homoglyphs.Replace(rest))
+}
+
+// trustedOwners are path prefixes where only a single organization can
+// publish modules.
+var trustedOwners = []string{
+	"cloud.google.com/go/",
+	"go.etcd.io/",
+	"go.opentelemetry.io/",
+	"go.uber.org/",
+	"golang.org/x/",
+	"google.golang.org/",
+	"k8s.io/",
+	"sigs.k8s.io/",
+}
+
+// trustedOwner returns the trusted owner prefix of a module root, or "".
+// e.g. golang.org/x/term -> golang.org/x/
+func trustedOwner(root string) string {
+	for _, prefix := range trustedOwners {
+		if strings.HasPrefix(root, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// maxDistance is the maximal edit distance of a look-alike of path, shorter
+// paths have less room for typos.
+func maxDistance(path string) int {
+	if len(path) < 16 {
+		return 1
+	}
+	return 2
+}
+
+// swapped reports whether a and b are host/owner/repo with owner and repo
+// swapped.
+func swapped(a, b string) bool {
+	fa, fb := strings.Split(a, "/"), strings.Split(b, "/")
+	if len(fa) != 3 || len(fb) != 3 || fa[0] != fb[0] {
+		return false
+	}
+	return strings.EqualFold(fa[1], fb[2]) && strings.EqualFold(fa[2], fb[1])
+}
+
+// isKnown reports whether root is a known module or inside one.
+func (c *squatChecker) isKnown(root string) bool {
+	for _, k := range c.known {
+		if root == k || strings.HasPrefix(root, k+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns warnings if path looks like a known module path.
+func (c *squatChecker) Check(path string) []Warning {
+	root := moduleRoot(path)
+	if c.isKnown(root) {
+		return nil
+	}
+
+	owner := trustedOwner(root)
+	var warnings []Warning
+	for _, k := range c.known {
+		// Only the owner can publish next to a trusted module.
+		if owner != "" && owner == trustedOwner(k) {
+			continue
+		}
+
+		switch {
+		case skeleton(root) == skeleton(k):
+			warnings = append(warnings, Warning{"homoglyph", fmt.Sprintf("%s looks like %s", root, k)})
+		case swapped(root, k):
+			warnings = append(warnings, Warning{"swapped", fmt.Sprintf("%s swaps owner and repository of %s", root, k)})
+		default:
+			if d := levenshtein(root, k); d <= maxDistance(k) {
+				warnings = append(warnings, Warning{"lookalike", fmt.Sprintf("%s is %d edit(s) away from %s", root, d, k)})
+			}
+		}
+	}
+	return warnings
+}
+
+// repoCreated returns the creation time of the GitHub repository, using the
+// cache.
+func repoCreated(ctx context.Context, owner, repo string, cache repoCache) (time.Time, error) {
+	r, err := repoMeta(ctx, owner, repo, cache)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return r.CreatedAt, nil
+}
+
+// newRepoWarning returns a warning if the repository was created less than
+// newRepoAge before now, or nil.
+func newRepoWarning(created, now time.Time) *Warning {
+	age := now.Sub(created)
+	if created.IsZero() || age >= newRepoAge {
+		return nil
+	}
+	days := int(age.Hours() / 24)
+	return &Warning{"new-repo", fmt.Sprintf("repository created %s (%d days ago)", created.Format(time.DateOnly), days)}
+}
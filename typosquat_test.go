@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+func TestSquatChecker(t *testing.T) {
+	allowlist := filepath.Join(t.TempDir(), "allow.txt")
+	if err := os.WriteFile(allowlist, []byte("# ours\ngithub.com/acme/logrus\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newSquatChecker(allowlist)
+	if err != nil {
+		t.Fatalf("newSquatChecker: %v", err)
+	}
+
+	cases := []struct {
+		path string
+		kind string // "" for no warning
+	}{
+		{"github.com/sirupsen/logrus", ""},
+		{"github.com/aws/aws-sdk-go-v2/service/s3", ""},
+		{"github.com/go-redis/redis/v8", ""},
+		{"gopkg.in/yaml.v1", ""},
+		{"github.com/acme/logrus", ""},
+		{"github.com/tebeka/atexit", ""},
+		// Same trusted owner as a known module.
+		{"golang.org/x/perf", ""},
+		{"google.golang.org/genai", ""},
+		{"k8s.io/apis", ""},
+		{"github.com/sirupsen/logrvs", "lookalike"},
+		{"github.com/stretchr/testfy", "lookalike"},
+		{"github.com/Sirupsen/logrus", "homoglyph"},
+		{"github.com/g00gle/uuid", "homoglyph"},
+		{"github.com/gоrilla/mux", "homoglyph"}, // Cyrillic о
+		{"github.com/logrus/sirupsen", "swapped"},
+		{"golang.org/x/term", ""},
+		{"go1ang.org/x/term", "lookalike"}, // hosts are not folded
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			warnings := c.Check(tc.path)
+			if tc.kind == "" {
+				if len(warnings) != 0 {
+					t.Fatalf("unexpected warnings: %v", warnings)
+				}
+				return
+			}
+
+			if len(warnings) == 0 || warnings[0].Kind != tc.kind {
+				t.Fatalf("expected %s warning, got %v", tc.kind, warnings)
+			}
+		})
+	}
+}
+
+func Test_reqInfoUnresolvedSquat(t *testing.T) {
+	c, err := newSquatChecker("")
+	if err != nil {
+		t.Fatalf("newSquatChecker: %v", err)
+	}
+	squats = c
+	t.Cleanup(func() { squats = nil })
+
+	cache := mapCache{m: map[string]string{"go.ubr.org/zap": "go.ubr.org"}}
+	req := requirement{Mod: module.Version{Path: "go.ubr.org/zap", Version: "v1.0.0"}}
+	ctx, cancel := testCtx(t)
+	defer cancel()
+
+	info, ok, err := reqInfo(ctx, req, "", cache)
+	if err != nil {
+		t.Fatalf("reqInfo: %v", err)
+	}
+	if !ok || len(info.Warnings) == 0 || info.Warnings[0].Kind != "lookalike" {
+		t.Fatalf("expected unresolved lookalike, got %v %+v", ok, info)
+	}
+}
+
+func TestServerTyposquat(t *testing.T) {
+	prev := checkTyposquat
+	checkTyposquat = true
+	t.Cleanup(func() { checkTyposquat, squats, policy.DenyWarnings = prev, nil, false })
+	if err := setupChecks(); err != nil {
+		t.Fatalf("setupChecks: %v", err)
+	}
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	srv.cache.Set("go.ubr.org/zap", "go.ubr.org")
+
+	w := httptest.NewRecorder()
+	body := `{"content": "module example.com/test\n\nrequire go.ubr.org/zap v1.0.0\n", "fields": ["name", "warnings"]}`
+	srv.routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/deps", strings.NewReader(body)))
+	if !strings.Contains(w.Body.String(), "lookalike") {
+		t.Fatalf("expected lookalike warning in API response:\n%s", w.Body.String())
+	}
+}
+
+func Test_levenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"logrus", "logrus", 0},
+		{"mux", "nux", 1},
+	}
+
+	for _, tc := range cases {
+		if got := levenshtein(tc.a, tc.b); got != tc.want {
+			t.Errorf("%q %q: expected %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+	}
+}
+
+func Test_newRepoWarning(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	if w := newRepoWarning(now.AddDate(0, 0, -10), now); w == nil || w.Kind != "new-repo" {
+		t.Fatalf("expected new-repo warning, got %v", w)
+	}
+	if w := newRepoWarning(now.AddDate(-1, 0, 0), now); w != nil {
+		t.Fatalf("unexpected warning: %v", w)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Usage is where the main module imports a dependency.
+type Usage struct {
+	Packages []string // importing packages in the main module
+	Sites    int      // number of import declarations
+}
+
+var showUsage bool
+
+// sourceImports maps an import path to the main module packages importing it
+// and the number of import sites.
+type sourceImports map[string]map[string]int
+
+// scanImports parses the .go files of the main module in dir, modPath is the
+// main module path.
+// Directories skipped by the scan (vendor, testdata ...) and nested modules
+// are ignored, files with syntax errors are skipped with a warning.
+func scanImports(dir, modPath string) (sourceImports, error) {
+	imports := make(sourceImports)
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if file == dir {
+				return nil
+			}
+			if skipDir(d.Name()) || isFile(filepath.Join(file, "go.mod")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(d.Name(), ".go") {
+			return nil
+		}
+
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			slog.Warn("can't parse, skipping", "file", file, "error", err)
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		pkg := path.Join(modPath, filepath.ToSlash(rel))
+
+		for _, spec := range f.Imports {
+			imp, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if imports[imp] == nil {
+				imports[imp] = make(map[string]int)
+			}
+			imports[imp][pkg]++
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// isFile reports whether path is an existing regular file.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// importModule returns the module in mods providing the import path, or "".
+// Nested modules are handled by picking the longest module path.
+func importModule(imp string, mods []string) string {
+	best := ""
+	for _, m := range mods {
+		if (imp == m || strings.HasPrefix(imp, m+"/")) && len(m) > len(best) {
+			best = m
+		}
+	}
+	return best
+}
+
+// addUsage sets the usage of the direct requirements in reqs from imports,
+// and returns the updated pkgs.
+// A direct requirement which is never imported gets an empty Usage, and is
+// added to pkgs if it's missing (e.g. unresolved).
+func addUsage(pkgs []PkgInfo, reqs []requirement, imports sourceImports) []PkgInfo {
+	mods := make([]string, len(reqs))
+	for i, r := range reqs {
+		mods[i] = r.Mod.Path
+	}
+
+	users := make(map[string]map[string]int) // module -> package -> sites
+	for imp, pkgSites := range imports {
+		mod := importModule(imp, mods)
+		if mod == "" {
+			continue
+		}
+		if users[mod] == nil {
+			users[mod] = make(map[string]int)
+		}
+		for pkg, n := range pkgSites {
+			users[mod][pkg] += n
+		}
+	}
+
+	index := make(map[string]int, len(pkgs))
+	for i, p := range pkgs {
+		index[p.Name] = i
+	}
+
+	for _, r := range reqs {
+		if r.Indirect {
+			continue
+		}
+
+		u := Usage{Packages: []string{}}
+		for pkg, n := range users[r.Mod.Path] {
+			u.Packages = append(u.Packages, pkg)
+			u.Sites += n
+		}
+		sort.Strings(u.Packages)
+
+		if i, ok := index[r.Mod.Path]; ok {
+			pkgs[i].Usage = &u
+			continue
+		}
+		if len(u.Packages) == 0 {
+			p := PkgInfo{Name: r.Mod.Path, Version: r.Mod.Version, Usage: &u}
+			if r.Replace != nil {
+				p.Replace = r.Replace.String()
+			}
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func Test_addUsage(t *testing.T) {
+	data, err := os.ReadFile("testdata/usage/go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imports, err := scanImports("testdata/usage", mainModule(data))
+	if err != nil {
+		t.Fatalf("scanImports: %v", err)
+	}
+
+	reqs, err := parseRequires(data, inputGoMod, true)
+	if err != nil {
+		t.Fatalf("parseRequires: %v", err)
+	}
+
+	// date/d is unresolved
+	pkgs := []PkgInfo{
+		{Name: "github.com/apple/a"},
+		{Name: "github.com/apple/a/sub"},
+		{Name: "github.com/banana/b"},
+		{Name: "github.com/cherry/c", Indirect: true},
+	}
+	pkgs = addUsage(pkgs, reqs, imports)
+
+	// broken.go import of banana/b is skipped
+	expected := []*Usage{
+		{Packages: []string{"example.com/usage", "example.com/usage/cmd/tool"}, Sites: 3},
+		{Packages: []string{"example.com/usage"}, Sites: 1},
+		{Packages: []string{}},
+		nil,
+		{Packages: []string{}},
+	}
+	if len(pkgs) != len(expected) {
+		t.Fatalf("expected %d packages, got %+v", len(expected), pkgs)
+	}
+	if d := pkgs[4]; d.Name != "github.com/date/d" || d.Version != "v0.3.0" {
+		t.Fatalf("unexpected unresolved package: %+v", d)
+	}
+	for i, p := range pkgs {
+		if !reflect.DeepEqual(p.Usage, expected[i]) {
+			t.Errorf("%s: expected %+v, got %+v", p.Name, expected[i], p.Usage)
+		}
+	}
+}
+
+func Test_importModule(t *testing.T) {
+	mods := []string{"github.com/apple/a", "github.com/apple/a/sub", "github.com/apple/ab"}
+	cases := map[string]string{
+		"github.com/apple/a":         "github.com/apple/a",
+		"github.com/apple/a/util":    "github.com/apple/a",
+		"github.com/apple/a/sub/x":   "github.com/apple/a/sub",
+		"github.com/apple/abc":       "",
+		"github.com/apple/ab/client": "github.com/apple/ab",
+	}
+
+	for imp, want := range cases {
+		if got := importModule(imp, mods); got != want {
+			t.Errorf("%s: expected %q, got %q", imp, want, got)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/semver"
+)
+
+// Vuln is a known vulnerability affecting a dependency version.
+type Vuln struct {
+	ID      string
+	Summary string
+	Fixed   string `json:",omitempty"` // first fixed version
+}
+
+// URL returns the vulnerability page.
+func (v Vuln) URL() string {
+	return "https://pkg.go.dev/vuln/" + v.ID
+}
+
+var (
+	checkVulns   bool
+	failOnVuln   bool
+	vulnDBPath   = "https://vuln.go.dev"
+	vulnDatabase *vulnDB // nil if not checking
+)
+
+// vulnDB is a Go vulnerability database in OSV format.
+// It is either a local directory, a zip file, or a URL.
+type vulnDB struct {
+	fsys fs.FS  // local database
+	base string // database URL
+
+	mu    sync.Mutex
+	index map[string][]string // module path -> vuln IDs, nil until loaded
+	loads flightGroup[map[string][]string]
+}
+
+// maxVulnDBFileSize limits the size of a remote database file, the module
+// index is a few MiB.
+const maxVulnDBFileSize = 64 << 20
+
+func openVulnDB(src string) (*vulnDB, error) {
+	if strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "http://") {
+		return &vulnDB{base: strings.TrimSuffix(src, "/")}, nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &vulnDB{fsys: os.DirFS(src)}, nil
+	}
+
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", src, err)
+	}
+	return &vulnDB{fsys: zr}, nil
+}
+
+// read returns the content of name in the database.
+func (db *vulnDB) read(ctx context.Context, name string) ([]byte, error) {
+	if db.fsys != nil {
+		return fs.ReadFile(db.fsys, name)
+	}
+
+	url := db.base + "/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req) //#nosec G704
+	if err != nil {
+		return nil, fmt.Errorf("GET %q - %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %q - %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxVulnDBFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("GET %q - %w", url, err)
+	}
+	if len(data) > maxVulnDBFileSize {
+		return nil, fmt.Errorf("GET %q - larger than %d bytes", url, maxVulnDBFileSize)
+	}
+	return data, nil
+}
+
+// loadIndex returns the module index, loading it on first use.
+// Failed loads are retried by the next caller. The load doesn't depend on
+// the cancellation of the caller's ctx, since the index is shared by all
+// requests in the web server.
+func (db *vulnDB) loadIndex(ctx context.Context) (map[string][]string, error) {
+	db.mu.Lock()
+	index := db.index
+	db.mu.Unlock()
+	if index != nil {
+		return index, nil
+	}
+
+	index, _, err := db.loads.Do(ctx, "index", func(ctx context.Context) (map[string][]string, error) {
+		ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+		defer cancel()
+		return db.readIndex(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	db.index = index
+	db.mu.Unlock()
+	return index, nil
+}
+
+func (db *vulnDB) readIndex(ctx context.Context) (map[string][]string, error) {
+	data, err := db.read(ctx, "index/modules.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var mods []struct {
+		Path  string
+		Vulns []struct {
+			ID string
+		}
+	}
+	if err := json.Unmarshal(data, &mods); err != nil {
+		return nil, fmt.Errorf("vulndb index: %w", err)
+	}
+
+	index := make(map[string][]string, len(mods))
+	for _, m := range mods {
+		for _, v := range m.Vulns {
+			index[m.Path] = append(index[m.Path], v.ID)
+		}
+	}
+	return index, nil
+}
+
+// osvEntry is an OSV entry, only the fields we use.
+type osvEntry struct {
+	ID       string
+	Summary  string
+	Details  string
+	Affected []struct {
+		Package struct {
+			Name      string
+			Ecosystem string
+		}
+		Ranges []struct {
+			Type   string
+			Events []struct {
+				Introduced string
+				Fixed      string
+			}
+		}
+	}
+}
+
+// affects returns whether the entry affects version of the module, and the
+// first fixed version after it.
+func (e osvEntry) affects(path, version string) (bool, string) {
+	version = strings.TrimSuffix(version, "+incompatible")
+	for _, a := range e.Affected {
+		if a.Package.Name != path {
+			continue
+		}
+
+		for _, r := range a.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+
+			// Events are ordered, each introduced opens a range closed by the
+			// following fixed.
+			affected, fixed := false, ""
+			for _, ev := range r.Events {
+				switch {
+				case ev.Introduced != "":
+					if semver.Compare(version, osvVersion(ev.Introduced)) >= 0 {
+						affected, fixed = true, ""
+					}
+				case ev.Fixed != "":
+					v := osvVersion(ev.Fixed)
+					if semver.Compare(version, v) >= 0 {
+						affected = false
+					} else if affected && fixed == "" {
+						fixed = v
+					}
+				}
+			}
+			if affected {
+				return true, fixed
+			}
+		}
+	}
+	return false, ""
+}
+
+// osvVersion converts OSV Go versions ("1.2.3", "0") to semver.
+func osvVersion(v string) string {
+	if v == "0" {
+		return "v0.0.0"
+	}
+	return "v" + v
+}
+
+// Vulns returns the known vulnerabilities of the module version.
+func (db *vulnDB) Vulns(ctx context.Context, path, version string) ([]Vuln, error) {
+	index, err := db.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var vulns []Vuln
+	for _, id := range index[path] {
+		data, err := db.read(ctx, idFile(id))
+		if err != nil {
+			return nil, err
+		}
+
+		var e osvEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+
+		if ok, fixed := e.affects(path, version); ok {
+			summary := e.Summary
+			if summary == "" {
+				summary, _, _ = strings.Cut(e.Details, "\n")
+			}
+			vulns = append(vulns, Vuln{ID: e.ID, Summary: summary, Fixed: fixed})
+		}
+	}
+	return vulns, nil
+}
+
+func idFile(id string) string {
+	return path.Join("ID", id+".json")
+}
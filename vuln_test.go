@@ -0,0 +1,203 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var vulnCases = []struct {
+	version string
+	ids     []string
+	fixed   string
+}{
+	{"v1.0.0", []string{"GO-2099-0001"}, "v1.0.5"},
+	{"v1.0.5", nil, ""},
+	{"v1.1.3", []string{"GO-2099-0001"}, "v1.2.4"},
+	{"v1.2.3", []string{"GO-2099-0001", "GO-2099-0002"}, "v1.2.4"},
+	{"v1.2.4", []string{"GO-2099-0002"}, ""},
+}
+
+func testVulns(t *testing.T, db *vulnDB) {
+	t.Helper()
+
+	for _, tc := range vulnCases {
+		t.Run(tc.version, func(t *testing.T) {
+			ctx, cancel := testCtx(t)
+			defer cancel()
+
+			vulns, err := db.Vulns(ctx, "github.com/apple/a", tc.version)
+			if err != nil {
+				t.Fatalf("Vulns: %v", err)
+			}
+
+			if len(vulns) != len(tc.ids) {
+				t.Fatalf("expected %v, got %+v", tc.ids, vulns)
+			}
+			for i, v := range vulns {
+				if v.ID != tc.ids[i] {
+					t.Fatalf("expected %v, got %+v", tc.ids, vulns)
+				}
+			}
+			if len(vulns) > 0 && vulns[0].Fixed != tc.fixed {
+				t.Fatalf("expected fixed %q, got %q", tc.fixed, vulns[0].Fixed)
+			}
+		})
+	}
+}
+
+func TestVulnDBDir(t *testing.T) {
+	db, err := openVulnDB("testdata/vulndb")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	testVulns(t, db)
+
+	ctx, cancel := testCtx(t)
+	defer cancel()
+	vulns, err := db.Vulns(ctx, "github.com/apple/a", "v1.2.5")
+	if err != nil {
+		t.Fatalf("Vulns: %v", err)
+	}
+	// No summary, first line of details
+	if len(vulns) != 1 || vulns[0].Summary != "Path traversal in archive extraction." {
+		t.Fatalf("unexpected vulns: %+v", vulns)
+	}
+}
+
+func TestVulnDBZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err := filepath.WalkDir("testdata/vulndb", func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel("testdata/vulndb", path)
+		w, err := zw.Create(filepath.ToSlash(name))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "vulndb.zip")
+	if err := os.WriteFile(fileName, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	db, err := openVulnDB(fileName)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	testVulns(t, db)
+}
+
+func TestVulnDBURL(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata/vulndb")))
+	defer ts.Close()
+
+	oldClient := httpClient
+	httpClient = ts.Client()
+	t.Cleanup(func() { httpClient = oldClient })
+
+	db, err := openVulnDB(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	testVulns(t, db)
+}
+
+func TestVulnDBURLRetry(t *testing.T) {
+	fail := true
+	files := http.FileServer(http.Dir("testdata/vulndb"))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index/modules.json" && fail {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	oldClient := httpClient
+	httpClient = ts.Client()
+	t.Cleanup(func() { httpClient = oldClient })
+
+	db, err := openVulnDB(ts.URL)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	// A canceled caller doesn't fail the load for others.
+	ctx, cancel := testCtx(t)
+	cancel()
+	if _, err := db.Vulns(ctx, "github.com/apple/a", "v1.0.0"); err == nil {
+		t.Fatal("expected error with canceled context")
+	}
+
+	if _, err := db.Vulns(t.Context(), "github.com/apple/a", "v1.0.0"); err == nil {
+		t.Fatal("expected error with database down")
+	}
+
+	fail = false
+	testVulns(t, db)
+}
+
+func Test_pkgsInfoVulns(t *testing.T) {
+	db, err := openVulnDB("testdata/vulndb")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	vulnDatabase = db
+	t.Cleanup(func() { vulnDatabase = nil })
+
+	cache := mapCache{m: map[string]string{
+		"apple/a":  "desc A",
+		"banana/b": "desc B",
+	}}
+	pkgs, err := pkgsInfo(t.Context(), strings.NewReader(testGoMod), cache)
+	if err != nil {
+		t.Fatalf("pkgsInfo: %v", err)
+	}
+
+	if len(pkgs[0].Vulns) != 2 || len(pkgs[1].Vulns) != 0 {
+		t.Fatalf("unexpected vulns: %+v", pkgs)
+	}
+
+	violations := Policy{DenyVulns: true}.Check(pkgs)
+	if len(violations) != 2 || violations[0].Rule != "vuln" {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+
+	var out bytes.Buffer
+	if err := writePkgs(&out, "example.com/test", pkgs, formatCycloneDX); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !strings.Contains(out.String(), `"recommendation": "Upgrade to v1.2.4"`) {
+		t.Fatalf("missing vulnerability in CycloneDX:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := writePkgs(&out, "example.com/test", pkgs, formatMarkdown); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !strings.Contains(out.String(), "**VULNERABILITY [GO-2099-0001](https://pkg.go.dev/vuln/GO-2099-0001)**") {
+		t.Fatalf("missing vulnerability in markdown:\n%s", out.String())
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
 	"errors"
@@ -8,42 +10,47 @@ import (
 	"html/template"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
 	"strings"
-	"time"
-
-	lru "github.com/hashicorp/golang-lru/v2"
+	"sync/atomic"
+	"syscall"
 )
 
 //go:embed templates
 var templatesFS embed.FS
 
 var (
-	pageTmpl    = template.Must(template.ParseFS(templatesFS, "templates/page.html"))
-	resultsTmpl = template.Must(template.ParseFS(templatesFS, "templates/results.html"))
+	pageTmpl    = template.Must(template.ParseFS(templatesFS, "templates/page.html", "templates/results.html", "templates/row.html"))
+	resultsTmpl = template.Must(template.ParseFS(templatesFS, "templates/results.html", "templates/row.html"))
+	streamTmpl  = template.Must(template.ParseFS(templatesFS, "templates/stream.html", "templates/row.html"))
 )
 
-type lruCache struct{ c *lru.Cache[string, string] }
-
-func (c *lruCache) Get(key string) (string, bool) { return c.c.Get(key) }
-func (c *lruCache) Set(key, value string)         { c.c.Add(key, value) }
-
-type server struct{ cache *lruCache }
-
-const maxFormBytes = 2 << 20
+type server struct {
+	cache   *serverCache
+	jobs    *jobs
+	limiter *rateLimiter   // nil if not limiting
+	badges  *rateLimiter   // per repository, nil if not limiting
+	trusted []netip.Prefix // proxies allowed to set X-Forwarded-For
+	ready   atomic.Bool    // set when the server accepts requests
+}
 
 var githubRawBase = "https://raw.githubusercontent.com"
 
 func newServer(cacheSize int) (*server, error) {
-	c, err := lru.New[string, string](cacheSize)
+	c, err := newServerCache(cacheSize, serverCacheTTL)
 	if err != nil {
 		return nil, err
 	}
-	return &server{cache: &lruCache{c: c}}, nil
+	return &server{cache: c, jobs: newJobs()}, nil
 }
 
-func (s *server) pkgsFromRequest(w http.ResponseWriter, r *http.Request) ([]PkgInfo, error) {
+// modFromRequest returns the go.mod content from the request repo or
+// content fields.
+func (s *server) modFromRequest(w http.ResponseWriter, r *http.Request) ([]byte, error) {
 	if r.Method == http.MethodPost {
 		r.Body = http.MaxBytesReader(w, r.Body, maxFormBytes)
 		if err := r.ParseForm(); err != nil {
@@ -60,44 +67,186 @@ func (s *server) pkgsFromRequest(w http.ResponseWriter, r *http.Request) ([]PkgI
 		return nil, fmt.Errorf("missing repo or content")
 	}
 
-	var rc io.ReadCloser
+	data := []byte(content)
 	if repo != "" {
-		uri := fmt.Sprintf("%s/%s/HEAD/go.mod", githubRawBase, repo)
-		var err error
-		rc, err = openURL(uri)
+		name, ref, err := parseRepoRef(repo)
+		if err != nil {
+			return nil, err
+		}
+		data, err = repoGoMod(r.Context(), name, ref)
 		if err != nil {
 			return nil, err
 		}
-		defer rc.Close()
-	} else {
-		rc = io.NopCloser(strings.NewReader(content))
 	}
-	return pkgsInfo(rc, s.cache)
+
+	if err := checkModules(data); err != nil {
+		return nil, err
+	}
+	if content != "" {
+		s.savePaste(data)
+	}
+	return data, nil
+}
+
+// requestErrorStatus is the HTTP status for errors of modFromRequest.
+func requestErrorStatus(err error) int {
+	if errors.Is(err, errTooManyModules) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func (s *server) pkgsFromRequest(w http.ResponseWriter, r *http.Request) ([]PkgInfo, error) {
+	data, err := s.modFromRequest(w, r)
+	if err != nil {
+		return nil, err
+	}
+	return pkgsInfo(r.Context(), bytes.NewReader(data), s.cache)
+}
+
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the server is ready to accept requests.
+func (s *server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
 }
 
 func (s *server) handlePage(w http.ResponseWriter, r *http.Request) {
-	if err := pageTmpl.Execute(w, nil); err != nil {
-		slog.Error("render page", "error", err)
+	renderPage(w, pageData{}, http.StatusOK)
+}
+
+// results is the data for resultsTmpl.
+type results struct {
+	Pkgs      []PkgInfo
+	Graph     string // Mermaid flowchart
+	Permalink string // GET URL of the results
+}
+
+// Weighted reports whether the packages have a dependency weight.
+func (r results) Weighted() bool {
+	for _, p := range r.Pkgs {
+		if p.Weight != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HasHealth reports whether the packages have a health score.
+func (r results) HasHealth() bool {
+	for _, p := range r.Pkgs {
+		if p.Health != nil {
+			return true
+		}
 	}
+	return false
+}
+
+// row is the data for the "row" template.
+type row struct {
+	PkgInfo
+	Weighted  bool
+	HasHealth bool
+}
+
+// Rows returns the table rows.
+func (r results) Rows() []row {
+	weighted, hasHealth := r.Weighted(), r.HasHealth()
+	rows := make([]row, len(r.Pkgs))
+	for i, p := range r.Pkgs {
+		rows[i] = row{p, weighted, hasHealth}
+	}
+	return rows
 }
 
 func (s *server) handleHTMX(w http.ResponseWriter, r *http.Request) {
-	pkgs, err := s.pkgsFromRequest(w, r)
+	data, err := s.modFromRequest(w, r)
 	if err != nil {
-		fmt.Fprint(w, `<p class="error">`)
-		template.HTMLEscape(w, []byte(err.Error()))
-		fmt.Fprint(w, `</p>`)
+		writeHTMLError(w, err)
 		return
 	}
-	if err := resultsTmpl.Execute(w, pkgs); err != nil {
+
+	res, err := s.results(r.Context(), data, r.FormValue("graph") != "")
+	if err != nil {
+		writeHTMLError(w, err)
+		return
+	}
+	res.Permalink = permalink(r)
+
+	if err := resultsTmpl.Execute(w, res); err != nil {
 		slog.Error("render results", "error", err)
 	}
 }
 
+// results describes the go.mod in data, with the dependency graph if graph
+// is set.
+func (s *server) results(ctx context.Context, data []byte, graph bool) (results, error) {
+	pkgs, err := pkgsInfo(ctx, bytes.NewReader(data), s.cache)
+	if err != nil {
+		return results{}, err
+	}
+
+	res := results{Pkgs: pkgs}
+	if graph {
+		var buf strings.Builder
+		g, err := s.graph(ctx, data)
+		if err == nil {
+			addWeights(res.Pkgs, g)
+			err = writeMermaid(&buf, g)
+		}
+		if err != nil {
+			slog.Warn("build graph", "error", err)
+		}
+		res.Graph = buf.String()
+	}
+	return res, nil
+}
+
+// graph builds and describes the dependency graph of data. Graph modules
+// count against maxModules: at most maxModules go.mod files are fetched, and
+// larger graphs are not described.
+func (s *server) graph(ctx context.Context, data []byte) (*depGraph, error) {
+	maxFetch := maxGraphNodes
+	if maxModules > 0 {
+		maxFetch = min(maxFetch, maxModules)
+	}
+	g, err := buildGraph(ctx, data, maxFetch, s.cache)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkModuleCount(len(g.Nodes) - 1); err != nil {
+		slog.Warn("graph not described", "error", err)
+		return g, nil
+	}
+	describeGraph(ctx, g, s.cache)
+	return g, nil
+}
+
+func writeHTMLError(w io.Writer, err error) {
+	fmt.Fprint(w, `<p class="error">`)
+	template.HTMLEscape(w, []byte(err.Error()))
+	fmt.Fprint(w, `</p>`)
+}
+
+// ndjsonType is the content type of newline delimited JSON.
+const ndjsonType = "application/x-ndjson"
+
 func (s *server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.Header.Get("Accept"), ndjsonType) {
+		s.handleAPIStream(w, r)
+		return
+	}
+
 	pkgs, err := s.pkgsFromRequest(w, r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), requestErrorStatus(err))
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -107,28 +256,134 @@ func (s *server) handleAPI(w http.ResponseWriter, r *http.Request) {
 }
 
 func serve(addr string) {
-	s, err := newServer(512)
+	if (tlsCert == "") != (tlsKey == "") {
+		fmt.Fprintf(os.Stderr, "error: TLS needs both -tls-cert and -tls-key\n")
+		os.Exit(1)
+	}
+
+	// Don't read server files named in submitted go list output.
+	readGoListMain = false
+
+	s, err := newServer(serverCacheSize)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %s\n", err)
 		os.Exit(1)
 	}
 
+	s.trusted, err = parsePrefixes(trustedProxies)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: trusted proxies: %s\n", err)
+		os.Exit(1)
+	}
+	if rateLimit > 0 {
+		s.limiter = newRateLimiter(rateLimit, rateBurst)
+	}
+	if badgeRateLimit > 0 {
+		s.badges = newRateLimiter(badgeRateLimit, rateBurst)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Before any background fetches, so they're counted and don't race it.
+	httpClient = &http.Client{Transport: &metricsTransport{next: httpClient.Transport}}
+
+	if serverCacheFile != "" {
+		if err := s.cache.load(serverCacheFile); err != nil {
+			slog.Warn("can't load cache", "file", serverCacheFile, "error", err)
+		}
+		slog.Info("cache loaded", "file", serverCacheFile, "entries", s.cache.Len())
+		go s.cache.flush(ctx, cacheFlushInterval)
+	}
+	if warmupFile != "" {
+		go func() {
+			if err := warmup(ctx, warmupFile, s.cache); err != nil {
+				slog.Warn("cache warmup", "file", warmupFile, "error", err)
+			}
+		}()
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = s.run(ctx, ln)
+	if err := s.cache.save(); err != nil {
+		slog.Warn("can't save cache", "file", serverCacheFile, "error", err)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// routes returns the server handler.
+func (s *server) routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /", s.handlePage)
-	mux.HandleFunc("POST /", s.handleHTMX)
-	mux.HandleFunc("POST /api", s.handleAPI)
+	mux.HandleFunc("POST /", s.limit(s.handleHTMX))
+	mux.HandleFunc("POST /api", s.limit(s.handleAPI))
+	mux.HandleFunc("POST /stream", s.limit(s.handleStream))
+	mux.HandleFunc("GET /events/{id}", s.handleEvents)
+	mux.HandleFunc("GET /r/{owner}/{repo...}", s.limit(s.handleRepoPage))
+	mux.HandleFunc("GET /s/{hash}", s.limit(s.handlePastePage))
+	mux.HandleFunc("GET /api/{owner}/{repo...}", s.limit(s.handleRepoAPI))
+	mux.HandleFunc("POST /api/v1/deps", s.limit(s.handleAPIv1))
+	mux.HandleFunc("GET /api/v1/openapi.json", handleOpenAPI)
+	mux.HandleFunc("GET /api/v1/{path...}", handleAPIv1NotFound)
+	mux.HandleFunc("POST /api/v1/{path...}", handleAPIv1NotFound)
+	// Badges are fetched by image proxies (e.g. GitHub camo) on behalf of
+	// many readers, limiting them per client would break READMEs. They're
+	// limited per repository instead, see handleBadge.
+	mux.HandleFunc("GET /badge/{owner}/{repo}", s.handleBadge)
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.HandleFunc("GET /metrics", handleMetrics)
+	return instrument(mux)
+}
+
+// run serves on ln until ctx is done, then stops accepting requests and waits
+// up to shutdownTimeout for the in-flight ones.
+func (s *server) run(ctx context.Context, ln net.Listener) error {
+	// Requests outliving the drain are canceled.
+	base, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	srv := &http.Server{
-		Addr:         addr,
-		Handler:      mux,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 2 * time.Minute,
-		IdleTimeout:  2 * time.Minute,
+		Handler:      s.routes(),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+		IdleTimeout:  idleTimeout,
+		BaseContext:  func(net.Listener) context.Context { return base },
 	}
 
-	slog.Info("listening", "addr", addr)
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		fmt.Fprintf(os.Stderr, "error: %s\n", err)
-		os.Exit(1)
+	errc := make(chan error, 1)
+	go func() {
+		if tlsCert != "" {
+			errc <- srv.ServeTLS(ln, tlsCert, tlsKey)
+			return
+		}
+		errc <- srv.Serve(ln)
+	}()
+	slog.Info("listening", "addr", ln.Addr().String(), "tls", tlsCert != "")
+	s.ready.Store(true)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", shutdownTimeout)
+	s.ready.Store(false)
+	sctx, scancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer scancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		slog.Warn("requests not drained", "error", err)
+		cancel()
+		return srv.Close()
 	}
+	return nil
 }
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 const testGoMod = `module example.com/test
@@ -155,7 +159,7 @@ func TestHandleAPITooLarge(t *testing.T) {
 		t.Fatalf("newServer: %v", err)
 	}
 
-	payload := "content=" + strings.Repeat("a", maxFormBytes+10)
+	payload := "content=" + strings.Repeat("a", int(maxFormBytes)+10)
 	req := httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(payload))
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	w := httptest.NewRecorder()
@@ -204,3 +208,88 @@ func TestHandleAPIMissingInput(t *testing.T) {
 		t.Fatalf("status: %d", w.Result().StatusCode)
 	}
 }
+
+func TestHandleHTMXGraph(t *testing.T) {
+	setupProxy(t, map[string]string{
+		"/github.com/apple/a/@v/v1.2.3.mod": "module github.com/apple/a\n",
+	})
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	srv.cache.Set("apple/a", "desc A")
+
+	form := url.Values{}
+	form.Set("graph", "1")
+	form.Set("content", `module example.com/test
+
+go 1.21
+
+require github.com/apple/a v1.2.3
+`)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	srv.handleHTMX(w, req)
+
+	body := w.Body.String()
+	if !strings.Contains(body, `<pre class="mermaid">flowchart LR`) {
+		t.Fatalf("expected mermaid graph in response:\n%s", body)
+	}
+}
+
+func TestServerRunDrains(t *testing.T) {
+	restore := setupGitHubHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = io.WriteString(w, `{"description":"Simple error handling primitives"}`)
+	})
+	defer restore()
+
+	srv, err := newServer(8)
+	if err != nil {
+		t.Fatalf("newServer: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	errc := make(chan error)
+	go func() { errc <- srv.run(ctx, ln) }()
+
+	type result struct {
+		code int
+		err  error
+	}
+	resc := make(chan result)
+	go func() {
+		form := url.Values{}
+		form.Set("content", "module example.com/test\n\nrequire github.com/pkg/errors v0.9.1\n")
+		resp, err := http.PostForm("http://"+ln.Addr().String()+"/api", form)
+		if err != nil {
+			resc <- result{err: err}
+			return
+		}
+		resp.Body.Close()
+		resc <- result{code: resp.StatusCode}
+	}()
+
+	// Shut down while the request waits for GitHub.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	res := <-resc
+	if res.err != nil || res.code != http.StatusOK {
+		t.Fatalf("expected in-flight request to finish, got %d (%v)", res.code, res.err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if srv.ready.Load() {
+		t.Fatal("expected server not ready after shutdown")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sort orders.
+const (
+	sortName   = "name"
+	sortWeight = "weight"
+)
+
+var (
+	showWeight bool
+	sortBy     = sortName
+)
+
+// Weight is the transitive weight of a direct dependency.
+type Weight struct {
+	// Total is the number of modules the dependency brings in.
+	Total int
+	// Unique are the modules brought in only by this dependency.
+	Unique []string `json:",omitempty"`
+	// Approximate is set when the graph was truncated, Total is a lower bound.
+	Approximate bool `json:",omitempty"`
+}
+
+// reachable returns the modules reachable from path in g, excluding path and
+// the main module.
+func (g *depGraph) reachable(path string) map[string]bool {
+	seen := map[string]bool{path: true, g.Main: true}
+	stack := []string{path}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dep := range g.Edges[p] {
+			if !seen[dep] {
+				seen[dep] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+
+	delete(seen, path)
+	delete(seen, g.Main)
+	return seen
+}
+
+// depWeights returns the weight of every direct dependency in g.
+func depWeights(g *depGraph) map[string]Weight {
+	direct := g.Edges[g.Main]
+	isDirect := make(map[string]bool, len(direct))
+	closures := make(map[string]map[string]bool, len(direct))
+	count := make(map[string]int)
+	for _, d := range direct {
+		isDirect[d] = true
+		closures[d] = g.reachable(d)
+		for p := range closures[d] {
+			count[p]++
+		}
+	}
+
+	weights := make(map[string]Weight, len(direct))
+	for _, d := range direct {
+		w := Weight{Total: len(closures[d]), Approximate: g.Truncated}
+		for p := range closures[d] {
+			if count[p] == 1 && !isDirect[p] {
+				w.Unique = append(w.Unique, p)
+			}
+		}
+		sort.Strings(w.Unique)
+		weights[d] = w
+	}
+	return weights
+}
+
+// addWeights sets the weight of pkgs from g.
+func addWeights(pkgs []PkgInfo, g *depGraph) {
+	weights := depWeights(g)
+	for i, p := range pkgs {
+		if w, ok := weights[p.Name]; ok {
+			pkgs[i].Weight = &w
+		}
+	}
+}
+
+// sortPkgs sorts pkgs by name, or by weight with the heaviest first.
+func sortPkgs(pkgs []PkgInfo, by string) error {
+	switch by {
+	case sortName:
+		sort.SliceStable(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	case sortWeight:
+		total := func(p PkgInfo) int {
+			if p.Weight == nil {
+				return 0
+			}
+			return p.Weight.Total
+		}
+		sort.SliceStable(pkgs, func(i, j int) bool {
+			wi, wj := total(pkgs[i]), total(pkgs[j])
+			if wi != wj {
+				return wi > wj
+			}
+			return pkgs[i].Name < pkgs[j].Name
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q (valid: %s, %s)", by, sortName, sortWeight)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+)
+
+func weightGraph() *depGraph {
+	g := newDepGraph("main")
+	edges := [][2]string{
+		{"main", "a"},
+		{"main", "b"},
+		{"a", "x"},
+		{"a", "y"},
+		{"x", "z"},
+		{"b", "y"},
+		{"b", "a"},
+		{"z", "main"},
+	}
+	for _, e := range edges {
+		g.addEdge(e[0], e[1])
+		g.Nodes[e[1]] = PkgInfo{Name: e[1]}
+	}
+	return g
+}
+
+func Test_depWeights(t *testing.T) {
+	weights := depWeights(weightGraph())
+
+	a := weights["a"]
+	if a.Total != 3 {
+		t.Fatalf("a: expected 3 deps, got %d", a.Total)
+	}
+	// b requires a, so nothing is unique to a
+	if len(a.Unique) != 0 {
+		t.Fatalf("a: unexpected unique %v", a.Unique)
+	}
+
+	b := weights["b"]
+	if b.Total != 4 {
+		t.Fatalf("b: expected 4 deps, got %d", b.Total)
+	}
+	if len(b.Unique) != 0 {
+		t.Fatalf("b: unexpected unique %v", b.Unique)
+	}
+}
+
+func Test_depWeightsUnique(t *testing.T) {
+	g := weightGraph()
+	g.Edges["b"] = []string{"y"}
+
+	weights := depWeights(g)
+	a := weights["a"]
+	if len(a.Unique) != 2 || a.Unique[0] != "x" || a.Unique[1] != "z" {
+		t.Fatalf("a: unexpected unique %v", a.Unique)
+	}
+	if b := weights["b"]; b.Total != 1 || len(b.Unique) != 0 {
+		t.Fatalf("b: unexpected %+v", b)
+	}
+}
+
+func Test_sortPkgs(t *testing.T) {
+	pkgs := []PkgInfo{
+		{Name: "a", Weight: &Weight{Total: 1}},
+		{Name: "b"},
+		{Name: "c", Weight: &Weight{Total: 7}},
+		{Name: "d", Weight: &Weight{Total: 1}},
+	}
+
+	if err := sortPkgs(pkgs, sortWeight); err != nil {
+		t.Fatalf("sort: %v", err)
+	}
+	var names string
+	for _, p := range pkgs {
+		names += p.Name
+	}
+	if names != "cadb" {
+		t.Fatalf("expected cadb, got %s", names)
+	}
+
+	if err := sortPkgs(pkgs, "size"); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// WorkspaceDep is a dependency shared by one or more modules in a workspace
+// or a scanned directory tree.
+type WorkspaceDep struct {
+	PkgInfo
+	// Versions maps requiring module path to the required version.
+	Versions map[string]string
+}
+
+// Skewed reports whether modules require different versions.
+func (d WorkspaceDep) Skewed() bool {
+	var first string
+	for _, v := range d.Versions {
+		if first == "" {
+			first = v
+			continue
+		}
+		if v != first {
+			return true
+		}
+	}
+	return false
+}
+
+// Users returns the modules requiring the dependency, sorted.
+func (d WorkspaceDep) Users() []string {
+	users := make([]string, 0, len(d.Versions))
+	for m := range d.Versions {
+		users = append(users, m)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// sharedRequire is a dependency required by one or more modules.
+type sharedRequire struct {
+	// Versions maps requiring module path to the required version.
+	Versions map[string]string
+	// Indirect is set if no module requires the dependency directly.
+	Indirect bool
+}
+
+func isWorkFile(name string) bool {
+	return filepath.Base(name) == "go.work" || strings.HasSuffix(name, ".work")
+}
+
+// workspaceRequires parses the go.work in fileName and returns the
+// requirements of every used module, keyed by dependency path.
+// Requirements on other workspace modules are dropped.
+func workspaceRequires(fileName string) (map[string]*sharedRequire, error) {
+	data, err := os.ReadFile(fileName) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+
+	wf, err := modfile.ParseWork(fileName, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Dir(fileName)
+	var mods []*modfile.File
+	for _, use := range wf.Use {
+		modName := filepath.Join(root, filepath.FromSlash(use.Path), "go.mod")
+		data, err := os.ReadFile(modName) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("use %q - %w", use.Path, err)
+		}
+
+		f, err := modfile.Parse(modName, data, nil)
+		if err != nil {
+			return nil, err
+		}
+		if f.Module == nil {
+			return nil, fmt.Errorf("%q: missing module directive", modName)
+		}
+		mods = append(mods, f)
+	}
+
+	return collectRequires(mods, wf.Replace, true), nil
+}
+
+// collectRequires returns the requirements of mods keyed by dependency path,
+// indirect ones only with includeIndirect.
+// replaces are applied after each module's own replace directives.
+// If skipLocal is set, requirements on one of mods are dropped.
+func collectRequires(mods []*modfile.File, replaces []*modfile.Replace, skipLocal bool) map[string]*sharedRequire {
+	local := make(map[string]bool)
+	if skipLocal {
+		for _, f := range mods {
+			local[f.Module.Mod.Path] = true
+		}
+	}
+
+	deps := make(map[string]*sharedRequire)
+	for _, f := range mods {
+		for _, require := range f.Require {
+			if require.Indirect && !includeIndirect {
+				continue
+			}
+
+			mod := applyReplace(require.Mod, f.Replace)
+			mod = applyReplace(mod, replaces)
+			if local[require.Mod.Path] || local[mod.Path] || mod.Version == "" {
+				continue
+			}
+
+			dep := deps[mod.Path]
+			if dep == nil {
+				dep = &sharedRequire{Versions: make(map[string]string), Indirect: true}
+				deps[mod.Path] = dep
+			}
+			dep.Versions[f.Module.Mod.Path] = mod.Version
+			dep.Indirect = dep.Indirect && require.Indirect
+		}
+	}
+
+	return deps
+}
+
+// applyReplace returns the replacement for mod, or mod if not replaced.
+// Replacements pinned to a version win over wildcard ones.
+// Local directory replacements have an empty version.
+func applyReplace(mod module.Version, replaces []*modfile.Replace) module.Version {
+	var match *modfile.Replace
+	for _, r := range replaces {
+		if r.Old.Path != mod.Path {
+			continue
+		}
+		if r.Old.Version == mod.Version {
+			match = r
+			break
+		}
+		if r.Old.Version == "" {
+			match = r
+		}
+	}
+
+	if match == nil {
+		return mod
+	}
+	return match.New
+}
+
+// workspaceInfo returns the deduplicated dependencies of the go.work in
+// fileName.
+func workspaceInfo(ctx context.Context, fileName string, cache repoCache) ([]WorkspaceDep, error) {
+	reqs, err := workspaceRequires(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedDepsInfo(ctx, reqs, cache)
+}
+
+// sharedDepsInfo returns the info for dependencies collected by
+// collectRequires, sorted by path.
+// They are described as single go.mod requirements, unresolved ones are
+// kept with keepUnresolved. When ctx is done, the dependencies resolved so
+// far are returned with ctx.Err().
+func sharedDepsInfo(ctx context.Context, reqs map[string]*sharedRequire, cache repoCache) ([]WorkspaceDep, error) {
+	paths := make([]string, 0, len(reqs))
+	for p := range reqs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var deps []WorkspaceDep
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return deps, err
+		}
+
+		shared := reqs[p]
+		req := requirement{
+			Mod:      module.Version{Path: p, Version: maxVersion(shared.Versions)},
+			Indirect: shared.Indirect,
+		}
+		info, ok, err := reqInfo(ctx, req, "", cache)
+		if ctx.Err() != nil {
+			return deps, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		deps = append(deps, WorkspaceDep{PkgInfo: info, Versions: shared.Versions})
+	}
+
+	return deps, nil
+}
+
+// maxVersion returns the highest version in versions, which is what MVS
+// would select for the workspace build.
+func maxVersion(versions map[string]string) string {
+	vs := make([]string, 0, len(versions))
+	for _, v := range versions {
+		vs = append(vs, v)
+	}
+	slices.SortFunc(vs, semver.Compare)
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[len(vs)-1]
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func Test_workspaceRequires(t *testing.T) {
+	deps, err := workspaceRequires("testdata/workspace/go.work")
+	if err != nil {
+		t.Fatalf("workspaceRequires: %v", err)
+	}
+
+	if _, ok := deps["example.com/b"]; ok {
+		t.Fatalf("workspace module listed as dependency")
+	}
+	if _, ok := deps["github.com/cherry/c"]; ok {
+		t.Fatalf("indirect dependency listed")
+	}
+
+	apple := deps["github.com/apple/a"].Versions
+	if apple["example.com/a"] != "v1.2.3" || apple["example.com/b"] != "v1.3.1" {
+		t.Fatalf("apple: unexpected versions %v", apple)
+	}
+
+	// go.work replace directive bumps a's version of banana
+	banana := deps["github.com/banana/b"].Versions
+	if banana["example.com/a"] != "v1.0.1" || banana["example.com/b"] != "v1.0.1" {
+		t.Fatalf("banana: unexpected versions %v", banana)
+	}
+}
+
+func Test_workspaceRequiresIndirect(t *testing.T) {
+	old := includeIndirect
+	includeIndirect = true
+	t.Cleanup(func() { includeIndirect = old })
+
+	deps, err := workspaceRequires("testdata/workspace/go.work")
+	if err != nil {
+		t.Fatalf("workspaceRequires: %v", err)
+	}
+
+	cherry, ok := deps["github.com/cherry/c"]
+	if !ok || !cherry.Indirect || cherry.Versions["example.com/a"] != "v0.1.0" {
+		t.Fatalf("cherry: unexpected %+v", cherry)
+	}
+	if deps["github.com/apple/a"].Indirect {
+		t.Fatal("apple: unexpected indirect")
+	}
+}
+
+func Test_workspaceInfoUnresolved(t *testing.T) {
+	old := keepUnresolved
+	includeIndirect, keepUnresolved = true, true
+	t.Cleanup(func() { includeIndirect, keepUnresolved = false, old })
+
+	// cherry has no description in the cache
+	restore := setupGitHubHTTP(t, http.NotFound)
+	defer restore()
+	cache := mapCache{m: map[string]string{
+		"apple/a":  "desc A",
+		"banana/b": "desc B",
+	}}
+
+	deps, err := workspaceInfo(t.Context(), "testdata/workspace/go.work", cache)
+	if err != nil {
+		t.Fatalf("workspaceInfo: %v", err)
+	}
+
+	if len(deps) != 3 {
+		t.Fatalf("expected 3 deps, got %+v", deps)
+	}
+	if cherry := deps[2]; cherry.Name != "github.com/cherry/c" || !cherry.Indirect || cherry.Version != "v0.1.0" {
+		t.Fatalf("cherry: unexpected %+v", cherry)
+	}
+}
+
+func Test_workspaceInfo(t *testing.T) {
+	cache := mapCache{m: map[string]string{
+		"apple/a":  "desc A",
+		"banana/b": "desc B",
+	}}
+
+	deps, err := workspaceInfo(t.Context(), "testdata/workspace/go.work", cache)
+	if err != nil {
+		t.Fatalf("workspaceInfo: %v", err)
+	}
+
+	if len(deps) != 2 {
+		t.Fatalf("expected 2 deps, got %d", len(deps))
+	}
+
+	apple, banana := deps[0], deps[1]
+	if apple.Name != "github.com/apple/a" || apple.Version != "v1.3.1" || apple.Desc != "desc A" {
+		t.Fatalf("apple: unexpected %+v", apple)
+	}
+	if !apple.Skewed() {
+		t.Fatalf("apple: expected version skew")
+	}
+	if banana.Skewed() {
+		t.Fatalf("banana: unexpected version skew")
+	}
+
+	users := apple.Users()
+	if len(users) != 2 || users[0] != "example.com/a" || users[1] != "example.com/b" {
+		t.Fatalf("apple: unexpected users %v", users)
+	}
+}
+
+func Test_workspaceInfoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	cache := cancelCache{
+		mapCache: mapCache{m: map[string]string{"apple/a": "desc A", "banana/b": "desc B"}},
+		key:      "banana/b",
+		cancel:   cancel,
+	}
+
+	deps, err := workspaceInfo(ctx, "testdata/workspace/go.work", cache)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(deps) != 1 || deps[0].Name != "github.com/apple/a" {
+		t.Fatalf("expected partial deps, got %+v", deps)
+	}
+}
+
+func Test_isWorkFile(t *testing.T) {
+	cases := map[string]bool{
+		"go.work":              true,
+		"testdata/go.work":     true,
+		"ci.work":              true,
+		"go.mod":               false,
+		"https://x.com/go.mod": false,
+	}
+	for name, expected := range cases {
+		if isWorkFile(name) != expected {
+			t.Errorf("%q: expected %v", name, expected)
+		}
+	}
+}